@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// Handler serves the admission webhooks of ConsolePlugin
+type Handler struct {
+	manager *plugin.ConsolePluginManager
+}
+
+func newHandler(manager *plugin.ConsolePluginManager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// admitFunc reviews an admission request and returns the admission response
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// serveAdmission decodes the AdmissionReview of the request, admits it and writes back the AdmissionReview
+func serveAdmission(request *restful.Request, response *restful.Response, admit admitFunc) {
+	review := &admissionv1.AdmissionReview{}
+	err := json.NewDecoder(request.Request.Body).Decode(review)
+	if err != nil || review.Request == nil {
+		zlog.Errorf("Error parsing AdmissionReview: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing AdmissionReview: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	admissionResponse := admit(review.Request)
+	admissionResponse.UID = review.Request.UID
+	review.Response = admissionResponse
+	review.Request = nil
+	_ = response.WriteHeaderAndEntity(http.StatusOK, review)
+}
+
+func decodeConsolePlugin(req *admissionv1.AdmissionRequest) (*plugin.ConsolePlugin, error) {
+	cp := &plugin.ConsolePlugin{}
+	if err := json.Unmarshal(req.Object.Raw, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func deny(err *apierrors.StatusError) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &err.ErrStatus,
+	}
+}
+
+func (h *Handler) validateConsolePlugin(request *restful.Request, response *restful.Response) {
+	serveAdmission(request, response, h.validate)
+}
+
+func (h *Handler) validate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	cp, err := decodeConsolePlugin(req)
+	if err != nil {
+		zlog.Errorf("Error decoding ConsolePlugin %s: %v", req.Name, err)
+		return deny(apierrors.NewBadRequest(fmt.Sprintf("error decoding ConsolePlugin: %v", err)))
+	}
+
+	if errs := h.manager.Validate(cp); len(errs) > 0 {
+		zlog.Warnf("Denied %s of ConsolePlugin %s: %v", req.Operation, cp.Name, errs.ToAggregate())
+		return deny(plugin.NewInvalidError(cp.Name, errs))
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// patchOperation is an operation of a JSON patch returned by the mutating admission webhook
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (h *Handler) mutateConsolePlugin(request *restful.Request, response *restful.Response) {
+	serveAdmission(request, response, h.mutate)
+}
+
+func (h *Handler) mutate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	original, err := decodeConsolePlugin(req)
+	if err != nil {
+		zlog.Errorf("Error decoding ConsolePlugin %s: %v", req.Name, err)
+		return deny(apierrors.NewBadRequest(fmt.Sprintf("error decoding ConsolePlugin: %v", err)))
+	}
+	var old *plugin.ConsolePlugin
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		old = &plugin.ConsolePlugin{}
+		if err = json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			zlog.Errorf("Error decoding old ConsolePlugin %s: %v", req.Name, err)
+			return deny(apierrors.NewBadRequest(fmt.Sprintf("error decoding old ConsolePlugin: %v", err)))
+		}
+	}
+
+	defaulted, _ := decodeConsolePlugin(req)
+	if err = h.manager.Default(defaulted, old); err != nil {
+		zlog.Errorf("Error defaulting ConsolePlugin %s: %v", req.Name, err)
+		return deny(apierrors.NewInternalError(err))
+	}
+
+	patch, err := json.Marshal(defaultingPatch(req.Object.Raw, original, defaulted))
+	if err != nil {
+		return deny(apierrors.NewInternalError(err))
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+// defaultingPatch returns the JSON patch operations turning the original ConsolePlugin into the defaulted one
+func defaultingPatch(raw []byte, original, defaulted *plugin.ConsolePlugin) []patchOperation {
+	patch := make([]patchOperation, 0)
+
+	// Enabled is not a pointer, so whether it is set could only be told from the raw object
+	var rawObject struct {
+		Spec map[string]json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &rawObject); err == nil && rawObject.Spec != nil {
+		if _, ok := rawObject.Spec["enabled"]; !ok {
+			patch = append(patch, patchOperation{Op: "add", Path: "/spec/enabled", Value: true})
+		}
+	}
+
+	if original.Spec.Order == nil && defaulted.Spec.Order != nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/order", Value: *defaulted.Spec.Order})
+	}
+
+	if original.Spec.Backend == nil || original.Spec.Backend.Service == nil {
+		return patch
+	}
+	originalSvc, defaultedSvc := original.Spec.Backend.Service, defaulted.Spec.Backend.Service
+	if originalSvc.Port != defaultedSvc.Port {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/backend/service/port",
+			Value: defaultedSvc.Port})
+	}
+	if originalSvc.BasePath != defaultedSvc.BasePath {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/backend/service/basePath",
+			Value: defaultedSvc.BasePath})
+	}
+	return patch
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"plugin-management-service/pkg/plugin"
+)
+
+var existingConsolePluginUnstructured = unstructured.Unstructured{
+	Object: map[string]interface{}{
+		"apiVersion": "console.openfuyao.com/v1beta1",
+		"kind":       "ConsolePlugin",
+		"metadata": map[string]interface{}{
+			"name": "existing-consoleplugin",
+		},
+		"spec": map[string]interface{}{
+			"pluginName":  "existing-consoleplugin",
+			"displayName": "Existing Plugin",
+			"entrypoint":  "Nav",
+			"order":       int64(3),
+			"backend": map[string]interface{}{
+				"type": "Service",
+				"service": map[string]interface{}{
+					"name":      "existing-svc",
+					"namespace": "existing-ns",
+				},
+			},
+			"enabled": true,
+		},
+	},
+}
+
+const testConsolePluginObject = `{
+	"apiVersion": "console.openfuyao.com/v1beta1",
+	"kind": "ConsolePlugin",
+	"metadata": {"name": "%s"},
+	"spec": {
+		"pluginName": "%s",
+		"displayName": "Test Plugin",
+		"entrypoint": "%s",
+		"subPages": [{"pageName": "page", "displayName": "Page"}],
+		"backend": {"type": "Service", "service": {"name": "svc", "namespace": "ns"}}
+	}
+}`
+
+const testConsolePluginDefaultsObject = `{
+	"apiVersion": "console.openfuyao.com/v1beta1",
+	"kind": "ConsolePlugin",
+	"metadata": {"name": "new-consoleplugin"},
+	"spec": {
+		"pluginName": "new-consoleplugin",
+		"displayName": "Test Plugin",
+		"entrypoint": "Nav",
+		%s
+		"backend": {"type": "Service", "service": {"name": "svc", "namespace": "ns"%s}}
+	}
+}`
+
+func initTestContainer() *restful.Container {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
+		},
+		&existingConsolePluginUnstructured,
+	)
+	ws := &restful.WebService{}
+	ws.Path("/rest/admission").Produces(restful.MIME_JSON)
+	BindAdmissionRoute(ws, &plugin.ConsolePluginManager{Client: client})
+	c := restful.NewContainer()
+	c.Add(ws)
+	return c
+}
+
+func newAdmissionReview(operation admissionv1.Operation, object string) []byte {
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("test-uid"),
+			Operation: operation,
+		},
+	}
+	review.APIVersion = "admission.k8s.io/v1"
+	review.Kind = "AdmissionReview"
+	if object != "" {
+		review.Request.Object.Raw = []byte(object)
+	}
+	data, _ := json.Marshal(review)
+	return data
+}
+
+func TestValidateConsolePlugin(t *testing.T) {
+	tests := []struct {
+		name        string
+		reqBody     []byte
+		wantStatus  int
+		wantAllowed bool
+		wantMessage string
+	}{
+		{
+			"TestInvalidBody",
+			[]byte("{"),
+			http.StatusBadRequest,
+			false,
+			"",
+		},
+		{
+			"TestDeleteAllowed",
+			newAdmissionReview(admissionv1.Delete, ""),
+			http.StatusOK,
+			true,
+			"",
+		},
+		{
+			"TestCreateAllowed",
+			newAdmissionReview(admissionv1.Create,
+				fmt.Sprintf(testConsolePluginObject, "new-consoleplugin", "new-consoleplugin", "Side")),
+			http.StatusOK,
+			true,
+			"",
+		},
+		{
+			"TestSubPagesOnNavDenied",
+			newAdmissionReview(admissionv1.Create,
+				fmt.Sprintf(testConsolePluginObject, "new-consoleplugin", "new-consoleplugin", "Nav")),
+			http.StatusOK,
+			false,
+			"spec.subPages",
+		},
+		{
+			"TestPathEntrypointDenied",
+			newAdmissionReview(admissionv1.Update,
+				fmt.Sprintf(testConsolePluginObject, "new-consoleplugin", "new-consoleplugin", "/")),
+			http.StatusOK,
+			false,
+			"spec.entrypoint",
+		},
+		{
+			"TestDuplicatePluginNameDenied",
+			newAdmissionReview(admissionv1.Create,
+				fmt.Sprintf(testConsolePluginObject, "another-consoleplugin", "existing-consoleplugin", "Side")),
+			http.StatusOK,
+			false,
+			"Duplicate value",
+		},
+		{
+			"TestUpdateExistingAllowed",
+			newAdmissionReview(admissionv1.Update,
+				fmt.Sprintf(testConsolePluginObject, "existing-consoleplugin", "existing-consoleplugin", "Side")),
+			http.StatusOK,
+			true,
+			"",
+		},
+	}
+	c := initTestContainer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/rest/admission/consoleplugins/validate",
+				bytes.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", restful.MIME_JSON)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tt.wantStatus)
+			}
+			if resp.Code != http.StatusOK {
+				return
+			}
+			review := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(resp.Body.Bytes(), &review); err != nil {
+				t.Fatal(err)
+			}
+			if review.Response == nil || review.Response.UID != "test-uid" {
+				t.Fatalf("unexpected admission response: %+v", review.Response)
+			}
+			if review.Response.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %t, want %t: %v", review.Response.Allowed, tt.wantAllowed, review.Response.Result)
+			}
+			if tt.wantMessage != "" && !strings.Contains(review.Response.Result.Message, tt.wantMessage) {
+				t.Errorf("message = %q, want containing %q", review.Response.Result.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMutateConsolePlugin(t *testing.T) {
+	tests := []struct {
+		name      string
+		reqBody   []byte
+		wantPatch string
+	}{
+		{
+			"TestDeleteNotPatched",
+			newAdmissionReview(admissionv1.Delete, ""),
+			"",
+		},
+		{
+			"TestAllDefaults",
+			newAdmissionReview(admissionv1.Create, fmt.Sprintf(testConsolePluginDefaultsObject, "", "")),
+			`[{"op":"add","path":"/spec/enabled","value":true},` +
+				`{"op":"add","path":"/spec/order","value":4},` +
+				`{"op":"add","path":"/spec/backend/service/port","value":80},` +
+				`{"op":"add","path":"/spec/backend/service/basePath","value":"/"}]`,
+		},
+		{
+			"TestNormalizeBasePath",
+			newAdmissionReview(admissionv1.Create, fmt.Sprintf(testConsolePluginDefaultsObject,
+				`"enabled": false, "order": 1,`, `, "port": 8080, "basePath": "//ui/"`)),
+			`[{"op":"add","path":"/spec/backend/service/basePath","value":"/ui"}]`,
+		},
+		{
+			"TestNothingToDefault",
+			newAdmissionReview(admissionv1.Update, fmt.Sprintf(testConsolePluginDefaultsObject,
+				`"enabled": true, "order": 0,`, `, "port": 8080, "basePath": "/ui"`)),
+			`[]`,
+		},
+	}
+	c := initTestContainer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/rest/admission/consoleplugins/mutate",
+				bytes.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", restful.MIME_JSON)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			if resp.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+			}
+			review := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(resp.Body.Bytes(), &review); err != nil {
+				t.Fatal(err)
+			}
+			if review.Response == nil || !review.Response.Allowed {
+				t.Fatalf("unexpected admission response: %+v", review.Response)
+			}
+			if string(review.Response.Patch) != tt.wantPatch {
+				t.Errorf("patch = %s, want %s", review.Response.Patch, tt.wantPatch)
+			}
+		})
+	}
+}
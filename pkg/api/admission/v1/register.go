@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+// Package v1 contains the admission webhook endpoints of ConsolePlugin
+package v1
+
+import (
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/plugin"
+)
+
+// BindAdmissionRoute define the webservice, route of admission webhooks
+func BindAdmissionRoute(webService *restful.WebService, manager *plugin.ConsolePluginManager) {
+	handler := newHandler(manager)
+
+	webService.Route(webService.POST("/consoleplugins/validate").
+		Doc("Validating admission webhook of ConsolePlugin").
+		To(handler.validateConsolePlugin))
+
+	webService.Route(webService.POST("/consoleplugins/mutate").
+		Doc("Mutating admission webhook of ConsolePlugin, which fills in the defaults").
+		To(handler.mutateConsolePlugin))
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	consolePluginResource = "consoleplugins"
+
+	// helmReleaseResource is the resource storing the Helm releases, like the Helm CLI does
+	helmReleaseResource = "secrets"
+)
+
+var errNoReviewer = errors.New("no access reviewer")
+
+// consolePluginAccess returns the access to the ConsolePlugin with the verb, to all ConsolePlugins if name is empty
+func consolePluginAccess(verb, name string) plugin.ConsolePluginAccess {
+	return plugin.ConsolePluginAccess{
+		Verb:     verb,
+		Group:    constant.CRDRepoGroup,
+		Resource: consolePluginResource,
+		Name:     name,
+	}
+}
+
+// releaseStorageAccess returns the access to the storage of the Helm releases in the namespace with the verb
+func releaseStorageAccess(verb, namespace string) plugin.ConsolePluginAccess {
+	return plugin.ConsolePluginAccess{
+		Verb:      verb,
+		Resource:  helmReleaseResource,
+		Namespace: namespace,
+	}
+}
+
+// authorize returns a route filter which lets the request through only if the user is allowed the verb on the
+// ConsolePlugin of the path, or on all ConsolePlugins if the path has none
+func (h *Handler) authorize(verb string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		if h.authorized(req, resp, consolePluginAccess(verb, req.PathParameter(constant.PluginName))) {
+			chain.ProcessFilter(req, resp)
+		}
+	}
+}
+
+// authorized reviews the accesses of the user of the request with the reviewer of the visibility filter,
+// responding with 401 if the request is anonymous, 403 if any access is denied and 503 if they could not
+// be reviewed
+func (h *Handler) authorized(request *restful.Request, response *restful.Response,
+	accesses ...plugin.ConsolePluginAccess) bool {
+	user, ok := auth.UserFrom(request.Request.Context())
+	if !ok {
+		respJson := &httputil.ResponseJson{
+			Code: constant.Unauthorized,
+			Msg:  "authentication is required",
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusUnauthorized, respJson)
+		return false
+	}
+	var reviewer plugin.AccessReviewer
+	if h.visibility != nil {
+		reviewer = h.visibility.Reviewer
+	}
+	for i := range accesses {
+		access := &accesses[i]
+		allowed, err := false, errNoReviewer
+		if reviewer != nil {
+			allowed, err = reviewer.Allowed(request.Request.Context(), user, access)
+		}
+		if err != nil {
+			zlog.Errorf("Error reviewing access of user %s: %v", sanitizeLogString(user.Username), err)
+			respJson := &httputil.ResponseJson{
+				Code: constant.ServiceUnavailable,
+				Msg:  "authorization is unavailable",
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+			return false
+		}
+		if !allowed {
+			respJson := &httputil.ResponseJson{
+				Code: constant.Forbidden,
+				Msg:  fmt.Sprintf("user %s is not allowed to %s", user.Username, describeAccess(access)),
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusForbidden, respJson)
+			return false
+		}
+	}
+	return true
+}
+
+// describeAccess returns the access as read in a sentence, e.g. "patch consoleplugins.console.openfuyao.com foo"
+func describeAccess(access *plugin.ConsolePluginAccess) string {
+	desc := access.Verb + " " + access.Resource
+	if access.Group != "" {
+		desc += "." + access.Group
+	}
+	if access.Name != "" {
+		desc += " " + access.Name
+	}
+	if access.Namespace != "" {
+		desc += " in namespace " + access.Namespace
+	}
+	return desc
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/plugin"
+)
+
+// fakeAccessReviewer allows the accesses in allowed, keyed by user and described access
+type fakeAccessReviewer struct {
+	allowed map[string]bool
+	err     error
+}
+
+func (r *fakeAccessReviewer) Allowed(ctx context.Context, user *authenticationv1.UserInfo,
+	access *plugin.ConsolePluginAccess) (bool, error) {
+	return r.allowed[user.Username+": "+describeAccess(access)], r.err
+}
+
+func withTestUser(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	if username := req.HeaderParameter("X-Test-User"); username != "" {
+		user := &authenticationv1.UserInfo{Username: username}
+		req.Request = req.Request.WithContext(auth.WithUser(req.Request.Context(), user))
+	}
+	chain.ProcessFilter(req, resp)
+}
+
+func TestHandlerAuthorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		url        string
+		reviewErr  error
+		noReviewer bool
+		wantCode   int
+	}{
+		{"TestAllowed", "admin", "/consoleplugins/test-consoleplugin", nil, false, http.StatusOK},
+		{"TestAllowedCollection", "admin", "/consoleplugins/order", nil, false, http.StatusOK},
+		{"TestDeniedName", "admin", "/consoleplugins/dummy-consoleplugin", nil, false, http.StatusForbidden},
+		{"TestDeniedUser", "user", "/consoleplugins/test-consoleplugin", nil, false, http.StatusForbidden},
+		{"TestAnonymous", "", "/consoleplugins/test-consoleplugin", nil, false, http.StatusUnauthorized},
+		{"TestReviewError", "admin", "/consoleplugins/test-consoleplugin", errors.New("timeout"), false,
+			http.StatusServiceUnavailable},
+		{"TestNoReviewer", "admin", "/consoleplugins/test-consoleplugin", nil, true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviewer := &fakeAccessReviewer{
+				allowed: map[string]bool{
+					"admin: patch consoleplugins.console.openfuyao.com test-consoleplugin": true,
+					"admin: patch consoleplugins.console.openfuyao.com":                    true,
+				},
+				err: tt.reviewErr,
+			}
+			handler := &Handler{visibility: &plugin.VisibilityFilter{Reviewer: reviewer}}
+			if tt.noReviewer {
+				handler.visibility = &plugin.VisibilityFilter{}
+			}
+			ok := func(req *restful.Request, resp *restful.Response) {
+				resp.WriteHeader(http.StatusOK)
+			}
+			ws := &restful.WebService{}
+			ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+			ws.Route(ws.PATCH("/consoleplugins/order").Filter(handler.authorize("patch")).To(ok))
+			ws.Route(ws.PATCH("/consoleplugins/{pluginName}").Filter(handler.authorize("patch")).To(ok))
+			c := restful.NewContainer()
+			c.Add(ws)
+			c.Filter(withTestUser)
+
+			req := httptest.NewRequest("PATCH", "http://example.com/rest/plugin-management/v1beta1"+tt.url, nil)
+			req.Header.Set("X-Test-User", tt.user)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.wantCode, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/utils/httputil"
+)
+
+// responseETag returns the strong ETag of a response, the hash of its rendered content. The trimmed views depend
+// on more than the resourceVersions, e.g. the console version, so the content is hashed instead.
+func responseETag(content interface{}) (string, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return strconv.Quote(hex.EncodeToString(sum[:])), nil
+}
+
+// renderedContent returns the view without the probe latency, which changes on every probe, so it does not defeat
+// the revalidation. The resourceVersion is kept, the clients sending it back as the precondition of their writes.
+func (cp ConsolePluginTrimmed) renderedContent() ConsolePluginTrimmed {
+	cp.LatencyMilliseconds = 0
+	return cp
+}
+
+// etagMatches reports whether the If-None-Match header matches the ETag, using the weak comparison of RFC 9110
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheableEntity responds with the ETag of the rendered content and the Cache-Control headers, or with
+// 304 Not Modified without body if the client already has the same content
+func writeCacheableEntity(request *restful.Request, response *restful.Response, respJson *httputil.ResponseJson,
+	rendered interface{}) {
+	etag, err := responseETag(rendered)
+	if err != nil {
+		_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+		return
+	}
+	response.Header().Set(constant.ETagHeader, etag)
+	response.Header().Set(constant.CacheControlHeader, constant.CacheControlRevalidate)
+	// the display names are localized by the Accept-Language header, the visibility depends on the user
+	response.Header().Set(constant.VaryHeader, strings.Join([]string{constant.AcceptLanguageHeader,
+		constant.AuthorizationHeader, constant.CookieHeader}, ", "))
+	if ifNoneMatch := request.HeaderParameter(constant.IfNoneMatchHeader); ifNoneMatch != "" &&
+		etagMatches(ifNoneMatch, etag) {
+		response.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"plugin-management-service/pkg/constant"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{"TestSame", `"abc"`, true},
+		{"TestWeak", `W/"abc"`, true},
+		{"TestList", `"xyz", "abc"`, true},
+		{"TestAny", `*`, true},
+		{"TestDifferent", `"xyz"`, false},
+		{"TestUnquoted", `abc`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, `"abc"`); got != tt.want {
+				t.Errorf("etagMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderedContentETag(t *testing.T) {
+	cp := ConsolePluginTrimmed{PluginName: "test", ResourceVersion: "1", LatencyMilliseconds: 10}
+	etag, err := responseETag(cp.renderedContent())
+	if err != nil {
+		t.Fatalf("responseETag() error = %v", err)
+	}
+
+	heartbeat := cp
+	heartbeat.LatencyMilliseconds = 20
+	if got, _ := responseETag(heartbeat.renderedContent()); got != etag {
+		t.Errorf("ETag changed with latency only")
+	}
+	updated := cp
+	updated.ResourceVersion = "2"
+	if got, _ := responseETag(updated.renderedContent()); got == etag {
+		t.Errorf("ETag did not change with resourceVersion")
+	}
+	changed := cp
+	changed.LastError = "connection refused"
+	if got, _ := responseETag(changed.renderedContent()); got == etag {
+		t.Errorf("ETag did not change with lastError")
+	}
+}
+
+func TestHandlerConditionalGet(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"TestList", "http://example.com/rest/plugin-management/v1beta1/consoleplugins"},
+		{"TestGet", "http://example.com/rest/plugin-management/v1beta1/consoleplugins/test-consoleplugin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initTestContainer()
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, httptest.NewRequest("GET", tt.url, nil))
+			etag := resp.Header().Get(constant.ETagHeader)
+			if resp.Code != http.StatusOK || etag == "" {
+				t.Fatalf("status code = %d, ETag = %q, want 200 with ETag", resp.Code, etag)
+			}
+			if got := resp.Header().Get(constant.CacheControlHeader); got != constant.CacheControlRevalidate {
+				t.Errorf("Cache-Control = %q, want %q", got, constant.CacheControlRevalidate)
+			}
+			if got, want := resp.Header().Get(constant.VaryHeader), "Accept-Language, Authorization, Cookie"; got != want {
+				t.Errorf("Vary = %q, want %q", got, want)
+			}
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			req.Header.Set(constant.IfNoneMatchHeader, etag)
+			resp = httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+				t.Errorf("status code = %d with %d bytes, want 304 without body", resp.Code, resp.Body.Len())
+			}
+
+			req = httptest.NewRequest("GET", tt.url, nil)
+			req.Header.Set(constant.IfNoneMatchHeader, `"stale"`)
+			resp = httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != http.StatusOK || resp.Header().Get(constant.ETagHeader) != etag {
+				t.Errorf("status code = %d, want 200 with the same ETag", resp.Code)
+			}
+		})
+	}
+
+	t.Run("TestChanged", func(t *testing.T) {
+		c := initTestContainer()
+		url := "http://example.com/rest/plugin-management/v1beta1/consoleplugins"
+		resp := httptest.NewRecorder()
+		c.Dispatch(resp, httptest.NewRequest("GET", url, nil))
+		etag := resp.Header().Get(constant.ETagHeader)
+
+		req := httptest.NewRequest("GET", url+"?enabled=true", nil)
+		req.Header.Set(constant.IfNoneMatchHeader, etag)
+		resp = httptest.NewRecorder()
+		c.Dispatch(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Errorf("status code = %d, want 200 for different content", resp.Code)
+		}
+	})
+}
@@ -14,13 +14,23 @@ package v1beta1
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/emicklei/go-restful/v3"
+	"golang.org/x/text/language"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/client/marketplace"
 	"plugin-management-service/pkg/constant"
 	"plugin-management-service/pkg/plugin"
 	"plugin-management-service/pkg/utils/httputil"
@@ -31,18 +41,164 @@ import (
 type Handler struct {
 	config  *rest.Config
 	manager *plugin.ConsolePluginManager
+
+	// backendURL resolves the URL of the backend serving the UI resources of a ConsolePlugin
+	backendURL func(cp *plugin.ConsolePlugin) string
+
+	manifests *plugin.ManifestFetcher
+
+	icons *plugin.IconFetcher
+
+	// visibility hides the ConsolePlugins and the sub-pages the calling user is not allowed to access
+	visibility *plugin.VisibilityFilter
+
+	// audit records the changes made to ConsolePlugins, nothing is recorded if nil
+	audit *plugin.AuditRecorder
+
+	// releases resolves the Helm releases which installed the ConsolePlugins
+	releases *plugin.ReleaseResolver
+
+	// releaseOperator uninstalls and rolls back the Helm releases which installed the ConsolePlugins
+	releaseOperator *plugin.ReleaseOperator
+
+	// marketplace pulls the charts of console extension to install
+	marketplace *marketplace.Client
+
+	// maxChartUploadBytes is the maximum size of a chart archive uploaded to install offline
+	maxChartUploadBytes int64
 }
 
-func newHandler(config *rest.Config) (*Handler, error) {
-	cm, err := plugin.NewConsolePluginManager(config)
-	if err != nil {
-		return nil, err
+func newHandler(config *rest.Config, manager *plugin.ConsolePluginManager, maxChartUploadBytes int64) *Handler {
+	var kubeClient kubernetes.Interface
+	if clientset, err := kubernetes.NewForConfig(config); err != nil {
+		zlog.Errorf("Error creating kubernetes client, icons in ConfigMaps could not be served: %v", err)
+	} else {
+		kubeClient = clientset
+	}
+	visibility := &plugin.VisibilityFilter{}
+	var audit *plugin.AuditRecorder
+	var releases *plugin.ReleaseResolver
+	var marketplaceClient *marketplace.Client
+	if kubeClient != nil {
+		visibility.Reviewer = plugin.NewSubjectAccessReviewer(kubeClient, plugin.DefaultAccessReviewTTL)
+		audit = plugin.NewAuditRecorder(kubeClient)
+		releases = plugin.NewReleaseResolver(kubeClient)
+		marketplaceClient = marketplace.NewClient(kubeClient)
 	}
+	manifests := plugin.NewManifestFetcher()
+	manifests.ConsoleVersion = manager.ConsoleVersion
 	return &Handler{
-		config:  config,
-		manager: cm,
-	}, nil
+		config:          config,
+		manager:         manager,
+		backendURL:      plugin.ConsolePluginLink,
+		manifests:       manifests,
+		icons:           plugin.NewIconFetcher(kubeClient),
+		visibility:      visibility,
+		audit:           audit,
+		releases:        releases,
+		releaseOperator: plugin.NewReleaseOperator(config, manager),
+		marketplace:     marketplaceClient,
+
+		maxChartUploadBytes: maxChartUploadBytes,
+	}
 }
+
+// writeCacheNotSynced responds with 503 if the error is caused by reading from an unsynced cache
+func writeCacheNotSynced(response *restful.Response, err error) bool {
+	if !errors.Is(err, plugin.ErrCacheNotSynced) {
+		return false
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.ServiceUnavailable,
+		Msg:  err.Error(),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+	return true
+}
+
+// FieldError describes an invalid field of a request
+type FieldError struct {
+	Field  string `json:"field"`
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+// parseListOptions reads the filters, sorting and pagination of a list request from the query parameters
+func parseListOptions(request *restful.Request) (*plugin.ListOptions, error) {
+	opts := &plugin.ListOptions{
+		Entrypoint: plugin.ConsolePluginEntrypoint(request.QueryParameter(constant.Entrypoint)),
+		Release:    request.QueryParameter(constant.Release),
+		Search:     request.QueryParameter(constant.Search),
+		SortBy:     request.QueryParameter(constant.SortBy),
+		Continue:   request.QueryParameter(constant.Continue),
+	}
+	if s := request.QueryParameter(constant.LabelSelector); s != "" {
+		selector, err := labels.Parse(s)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid labelSelector: %v", err))
+		}
+		opts.LabelSelector = selector
+	}
+	if s := request.QueryParameter(constant.Enabled); s != "" {
+		enabled, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid enabled %q", s))
+		}
+		opts.Enabled = &enabled
+	}
+	if s := request.QueryParameter(constant.Limit); s != "" {
+		limit, err := strconv.ParseInt(s, constant.BaseTen, 64)
+		if err != nil || limit < 0 {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid limit %q", s))
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
+
+// writeManagerError responds with the status code matching the error returned by the ConsolePluginManager
+func writeManagerError(response *restful.Response, msg string, err error) {
+	zlog.Errorf("%s: %v", msg, err)
+	respJson := &httputil.ResponseJson{
+		Code: constant.ServerError,
+		Msg:  fmt.Sprintf("%s: %v", msg, err),
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, plugin.ErrCacheNotSynced):
+		respJson.Code, status = constant.ServiceUnavailable, http.StatusServiceUnavailable
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		respJson.Code, status = constant.ClientError, http.StatusBadRequest
+		respJson.Data = fieldErrors(err)
+	case apierrors.IsNotFound(err):
+		respJson.Code, status = constant.ResourceNotFound, http.StatusNotFound
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		respJson.Code, status = constant.Conflict, http.StatusConflict
+	case apierrors.IsUnauthorized(err):
+		respJson.Code, status = constant.Unauthorized, http.StatusUnauthorized
+	case apierrors.IsForbidden(err):
+		respJson.Code, status = constant.Forbidden, http.StatusForbidden
+	}
+	_ = response.WriteHeaderAndEntity(status, respJson)
+}
+
+// fieldErrors extracts the field-level causes of an API status error
+func fieldErrors(err error) []FieldError {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || statusErr.Status().Details == nil {
+		return nil
+	}
+	var fieldErrs []FieldError
+	for _, cause := range statusErr.Status().Details.Causes {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:  cause.Field,
+			Type:   string(cause.Type),
+			Detail: cause.Message,
+		})
+	}
+	return fieldErrs
+}
+
 func formatOrder(order *int64) *string {
 	if order != nil {
 		formattedOrder := strconv.FormatInt(*order, constant.BaseTen)
@@ -74,18 +230,87 @@ func sanitizeLogString(input string) string {
 
 // ConsolePluginTrimmed contains only the essential info of a consoleplugin for front-end
 type ConsolePluginTrimmed struct {
-	Release     string                     `json:"release"`
-	DisplayName string                     `json:"displayName"`
-	PluginName  string                     `json:"pluginName"`
-	Order       *string                    `json:"order,omitempty"`
-	SubPages    []plugin.ConsolePluginName `json:"subPages"`
-	Entrypoint  string                     `json:"entrypoint"`
-	URL         string                     `json:"url"`
-	Enabled     bool                       `json:"enabled"`
+	Release             string                     `json:"release"`
+	DisplayName         string                     `json:"displayName"`
+	PluginName          string                     `json:"pluginName"`
+	Order               *string                    `json:"order,omitempty"`
+	SubPages            []plugin.ConsolePluginName `json:"subPages"`
+	Entrypoint          string                     `json:"entrypoint"`
+	URL                 string                     `json:"url"`
+	Enabled             bool                       `json:"enabled"`
+	Ready               bool                       `json:"ready"`
+	Conditions          []metav1.Condition         `json:"conditions,omitempty"`
+	LatencyMilliseconds int64                      `json:"latencyMilliseconds,omitempty"`
+	LastError           string                     `json:"lastError,omitempty"`
+	HasIcon             bool                       `json:"hasIcon,omitempty"`
+	Reason              string                     `json:"reason,omitempty"`
+	Message             string                     `json:"message,omitempty"`
+	ResourceVersion     string                     `json:"resourceVersion,omitempty"`
+}
+
+// preferredLanguages returns the languages preferred by the client, from the lang query parameter or the
+// Accept-Language header
+func preferredLanguages(request *restful.Request) []language.Tag {
+	return plugin.PreferredLanguages(request.QueryParameter(constant.Lang),
+		request.HeaderParameter(constant.AcceptLanguageHeader))
+}
+
+// newConsolePluginTrimmed returns the view of a ConsolePlugin for the front-end, with the display names in the
+// preferred languages. A ConsolePlugin incompatible with the console version is marked with the Incompatible
+// reason and reported as disabled.
+func (h *Handler) newConsolePluginTrimmed(cp *plugin.ConsolePlugin, langs []language.Tag) ConsolePluginTrimmed {
+	cpTrimmed := ConsolePluginTrimmed{
+		DisplayName:         plugin.LocalizeDisplayName(cp.Spec.DisplayName, cp.Spec.DisplayNames, langs),
+		PluginName:          cp.Spec.PluginName,
+		Order:               formatOrder(cp.Spec.Order),
+		SubPages:            plugin.LocalizeSubPages(cp.Spec.SubPages, langs),
+		Entrypoint:          string(cp.Spec.Entrypoint),
+		URL:                 cp.Status.Link,
+		Enabled:             cp.Spec.Enabled,
+		Ready:               plugin.IsConsolePluginReady(cp),
+		Conditions:          cp.Status.Conditions,
+		LatencyMilliseconds: cp.Status.LatencyMilliseconds,
+		LastError:           cp.Status.LastError,
+		ResourceVersion:     cp.ResourceVersion,
+		HasIcon:             cp.Spec.Icon != nil,
+	}
+	if releaseName, ok := cp.ObjectMeta.Annotations[plugin.ReleaseNameAnnotation]; ok {
+		cpTrimmed.Release = releaseName
+	}
+	if err := h.manager.CheckCompatibility(cp); err != nil {
+		cpTrimmed.Enabled = false
+		cpTrimmed.Reason = plugin.ReasonIncompatible
+		cpTrimmed.Message = err.Error()
+	}
+	return cpTrimmed
 }
 
 func (h *Handler) listConsolePlugins(request *restful.Request, response *restful.Response) {
-	consolePlugins, err := h.manager.ListConsolePlugins()
+	if request.QueryParameter(constant.Watch) == "true" {
+		h.watchConsolePlugins(request, response)
+		return
+	}
+
+	opts, err := parseListOptions(request)
+	if err != nil {
+		writeManagerError(response, "Invalid list options", err)
+		return
+	}
+	ctx := request.Request.Context()
+	user, _ := auth.UserFrom(ctx)
+	langs := preferredLanguages(request)
+	opts.Languages = langs
+	opts.Visible = func(cp *plugin.ConsolePlugin) (*plugin.ConsolePlugin, bool) {
+		return h.visibility.FilterOne(ctx, user, cp)
+	}
+	page, err := h.manager.ListConsolePluginsPage(opts)
+	if writeCacheNotSynced(response, err) {
+		return
+	}
+	if apierrors.IsBadRequest(err) {
+		writeManagerError(response, "Invalid list options", err)
+		return
+	}
 	if err != nil {
 		zlog.Errorf("Error listing ConsolePlugins: %v", err)
 		respJson := &httputil.ResponseJson{
@@ -97,20 +322,17 @@ func (h *Handler) listConsolePlugins(request *restful.Request, response *restful
 	}
 
 	consolePluginsTrimmed := make([]ConsolePluginTrimmed, 0)
-	for _, cp := range consolePlugins {
-		cpTrimmed := ConsolePluginTrimmed{
-			DisplayName: cp.Spec.DisplayName,
-			PluginName:  cp.Spec.PluginName,
-			Order:       formatOrder(cp.Spec.Order),
-			SubPages:    cp.Spec.SubPages,
-			Entrypoint:  string(cp.Spec.Entrypoint),
-			URL:         cp.Status.Link,
-			Enabled:     cp.Spec.Enabled,
-		}
-		if releaseName, ok := cp.ObjectMeta.Annotations["meta.helm.sh/release-name"]; ok {
-			cpTrimmed.Release = releaseName
-		}
+	rendered := make([]ConsolePluginTrimmed, 0, len(page.Items))
+	for i := range page.Items {
+		cpTrimmed := h.newConsolePluginTrimmed(&page.Items[i], langs)
 		consolePluginsTrimmed = append(consolePluginsTrimmed, cpTrimmed)
+		rendered = append(rendered, cpTrimmed.renderedContent())
+	}
+	if page.Continue != "" {
+		response.AddHeader(constant.ContinueHeader, page.Continue)
+	}
+	if page.RemainingItemCount != nil {
+		response.AddHeader(constant.RemainingItemCountHeader, strconv.FormatInt(*page.RemainingItemCount, constant.BaseTen))
 	}
 
 	respJson := &httputil.ResponseJson{
@@ -118,52 +340,88 @@ func (h *Handler) listConsolePlugins(request *restful.Request, response *restful
 		Msg:  "success",
 		Data: consolePluginsTrimmed,
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+	writeCacheableEntity(request, response, respJson, rendered)
 }
 
-func (h *Handler) getConsolePlugin(request *restful.Request, response *restful.Response) {
+// visibleConsolePlugin returns the ConsolePlugin of the request with the sub-pages visible to the user, responding
+// with 404 if it is not installed or hidden from the user, so its existence is not revealed
+func (h *Handler) visibleConsolePlugin(request *restful.Request, response *restful.Response) (*plugin.ConsolePlugin,
+	bool) {
 	pluginName := request.PathParameter(constant.PluginName)
-	consolePlugin, err := h.manager.GetConsolePlugin(pluginName)
+	cp, err := h.manager.GetConsolePlugin(pluginName)
 	if err != nil {
-		zlog.Errorf("Error getting ConsolePlugin: %v", err)
+		writeManagerError(response, "Error getting ConsolePlugin", err)
+		return nil, false
+	}
+	ctx := request.Request.Context()
+	user, _ := auth.UserFrom(ctx)
+	visible, ok := h.visibility.FilterOne(ctx, user, cp)
+	if !ok {
 		respJson := &httputil.ResponseJson{
 			Code: constant.ResourceNotFound,
-			Msg:  fmt.Sprintf("Error getting ConsolePlugin: %v", err),
+			Msg:  fmt.Sprintf("Error getting ConsolePlugin: %s not found", pluginName),
 		}
 		_ = response.WriteHeaderAndEntity(http.StatusNotFound, respJson)
-		return
+		return nil, false
 	}
+	return visible, true
+}
 
-	consolePluginTrimmed := ConsolePluginTrimmed{
-		DisplayName: consolePlugin.Spec.DisplayName,
-		PluginName:  consolePlugin.Spec.PluginName,
-		Order:       formatOrder(consolePlugin.Spec.Order),
-		SubPages:    consolePlugin.Spec.SubPages,
-		Entrypoint:  string(consolePlugin.Spec.Entrypoint),
-		URL:         consolePlugin.Status.Link,
-		Enabled:     consolePlugin.Spec.Enabled,
-	}
-	if releaseName, ok := consolePlugin.ObjectMeta.Annotations["meta.helm.sh/release-name"]; ok {
-		consolePluginTrimmed.Release = releaseName
+func (h *Handler) getConsolePlugin(request *restful.Request, response *restful.Response) {
+	consolePlugin, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
 	}
 
+	consolePluginTrimmed := h.newConsolePluginTrimmed(consolePlugin, preferredLanguages(request))
+
 	respJson := &httputil.ResponseJson{
 		Code: constant.Success,
 		Msg:  "success",
 		Data: consolePluginTrimmed,
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+	writeCacheableEntity(request, response, respJson, consolePluginTrimmed.renderedContent())
 }
 
 type setEnablementBody struct {
 	PluginName string `json:"pluginName"`
 	Enabled    bool   `json:"enabled"`
+
+	// ResourceVersion is the precondition of the change if not empty, it could also be sent as If-Match header
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// enablementPrecondition returns the resourceVersion which the ConsolePlugin is expected to have
+func enablementPrecondition(request *restful.Request, body *setEnablementBody) (string, error) {
+	ifMatch := strings.Trim(strings.TrimSpace(request.HeaderParameter(constant.IfMatchHeader)), `"`)
+	if ifMatch == "*" {
+		ifMatch = ""
+	}
+	if ifMatch != "" && body.ResourceVersion != "" && ifMatch != body.ResourceVersion {
+		return "", apierrors.NewBadRequest(fmt.Sprintf("If-Match %s does not match resourceVersion %s",
+			ifMatch, body.ResourceVersion))
+	}
+	if body.ResourceVersion != "" {
+		return body.ResourceVersion, nil
+	}
+	return ifMatch, nil
+}
+
+// setEnablementResult lists the ConsolePlugins whose enablement was changed, including the dependencies
+// or dependents changed in cascade
+type setEnablementResult struct {
+	PluginName string   `json:"pluginName"`
+	Enabled    bool     `json:"enabled"`
+	Changed    []string `json:"changed"`
 }
 
 func (h *Handler) checkEnablement(request *restful.Request, response *restful.Response) {
 	pluginName := request.PathParameter(constant.PluginName)
 
 	pluginEnabled, err := h.manager.CheckPluginEnablementIfInstalled(pluginName)
+	if writeCacheNotSynced(response, err) {
+		return
+	}
 	if err != nil {
 		zlog.Errorf("Error checking ConsolePlugin enablement: %v", err)
 		respJson := &httputil.ResponseJson{
@@ -211,8 +469,30 @@ func (h *Handler) setEnablement(request *restful.Request, response *restful.Resp
 		return
 	}
 
+	resourceVersion, err := enablementPrecondition(request, body)
+	if err != nil {
+		writeManagerError(response, "Invalid precondition", err)
+		return
+	}
+
 	enabledBool := body.Enabled
-	err = h.manager.SetPluginEnablementIfInstalled(pluginName, enabledBool)
+	cascade := request.QueryParameter(constant.Cascade) == "true"
+	previous := h.enablementSnapshot()
+	changed, err := h.manager.SetPluginEnablementIfMatch(pluginName, enabledBool, cascade, resourceVersion)
+	// a cascade may fail after some dependencies were changed, which are recorded all the same
+	for _, name := range changed {
+		h.audit.Record(request.Request.Context(), newEnablementChange(request, name, previous, enabledBool))
+	}
+	if writeCacheNotSynced(response, err) {
+		return
+	}
+	if err != nil && len(changed) > 0 {
+		err = fmt.Errorf("%w, after setting the enablement of %s", err, strings.Join(changed, ", "))
+	}
+	if apierrors.IsConflict(err) {
+		writeManagerError(response, "Fail to set the ConsolePlugin enablement", err)
+		return
+	}
 	if err != nil {
 		zlog.Errorf("Error setting ConsolePlugin enablement: %v", err)
 		respJson := &httputil.ResponseJson{
@@ -227,6 +507,263 @@ func (h *Handler) setEnablement(request *restful.Request, response *restful.Resp
 	respJson := &httputil.ResponseJson{
 		Code: constant.Success,
 		Msg:  fmt.Sprintf("Set ConsolePlugin %s enablement to %t", pluginName, enabledBool),
+		Data: &setEnablementResult{
+			PluginName: pluginName,
+			Enabled:    enabledBool,
+			Changed:    changed,
+		},
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// newConsolePluginSpecBody returns a spec to decode a request body into, Enabled defaults to true
+func newConsolePluginSpecBody() *plugin.ConsolePluginSpec {
+	return &plugin.ConsolePluginSpec{
+		Enabled: true,
+	}
+}
+
+func (h *Handler) createConsolePlugin(request *restful.Request, response *restful.Response) {
+	spec := newConsolePluginSpecBody()
+	err := json.NewDecoder(request.Request.Body).Decode(spec)
+	if err != nil {
+		zlog.Errorf("Error parsing request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	consolePlugin, err := h.manager.CreateConsolePlugin(spec)
+	if err != nil {
+		writeManagerError(response, "Error creating ConsolePlugin", err)
+		return
+	}
+
+	record := newChangeRecord(request, consolePlugin.Name, plugin.ChangeCreated)
+	record.Fields = []string{"spec.enabled"}
+	record.NewValue = strconv.FormatBool(consolePlugin.Spec.Enabled)
+	h.audit.Record(request.Request.Context(), record)
+	zlog.Infof("Successfully created ConsolePlugin %s", consolePlugin.Name)
+	respJson := &httputil.ResponseJson{
+		Code: constant.FileCreated,
+		Msg:  "success",
+		Data: h.newConsolePluginTrimmed(consolePlugin, preferredLanguages(request)),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusCreated, respJson)
+}
+
+func (h *Handler) updateConsolePlugin(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+
+	spec := newConsolePluginSpecBody()
+	err := json.NewDecoder(request.Request.Body).Decode(spec)
+	if err != nil {
+		zlog.Errorf("Error parsing request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	if pluginName != spec.PluginName {
+		sanitizedBodyPluginName := sanitizeLogString(spec.PluginName)
+		zlog.Errorf("PluginName not match: %s, %s", pluginName, sanitizedBodyPluginName)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("PluginName not match: %s, %s", pluginName, sanitizedBodyPluginName),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	old, _ := h.manager.GetConsolePlugin(pluginName)
+	consolePlugin, err := h.manager.UpdateConsolePlugin(pluginName, spec)
+	if err != nil {
+		writeManagerError(response, "Error updating ConsolePlugin", err)
+		return
+	}
+	h.recordSpecChange(request, old, consolePlugin, plugin.ChangeUpdated)
+
+	zlog.Infof("Successfully updated ConsolePlugin %s", pluginName)
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: h.newConsolePluginTrimmed(consolePlugin, preferredLanguages(request)),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+func (h *Handler) patchConsolePlugin(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+
+	patch, err := io.ReadAll(request.Request.Body)
+	if err != nil {
+		zlog.Errorf("Error reading request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error reading request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	old, _ := h.manager.GetConsolePlugin(pluginName)
+	consolePlugin, err := h.manager.PatchConsolePluginSpec(pluginName, patch)
+	if err != nil {
+		writeManagerError(response, "Error patching ConsolePlugin", err)
+		return
+	}
+	h.recordSpecChange(request, old, consolePlugin, plugin.ChangeUpdated)
+
+	zlog.Infof("Successfully patched ConsolePlugin %s", pluginName)
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: h.newConsolePluginTrimmed(consolePlugin, preferredLanguages(request)),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+func (h *Handler) deleteConsolePlugin(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+
+	err := h.manager.DeleteConsolePlugin(pluginName)
+	if err != nil {
+		writeManagerError(response, "Error deleting ConsolePlugin", err)
+		return
+	}
+
+	h.audit.Record(request.Request.Context(), newChangeRecord(request, pluginName, plugin.ChangeDeleted))
+	zlog.Infof("Successfully deleted ConsolePlugin %s", pluginName)
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  fmt.Sprintf("Deleted ConsolePlugin %s", pluginName),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// reorderBody is the list of ConsolePlugins in the expected menu order
+type reorderBody struct {
+	PluginNames []string `json:"pluginNames"`
+}
+
+func (h *Handler) reorderConsolePlugins(request *restful.Request, response *restful.Response) {
+	body := &reorderBody{}
+	if err := json.NewDecoder(request.Request.Body).Decode(body); err != nil {
+		zlog.Errorf("Error parsing request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+
+	oldPlugins, _ := h.manager.ListConsolePlugins()
+	consolePlugins, err := h.manager.ReorderConsolePlugins(body.PluginNames)
+	if err != nil {
+		writeManagerError(response, "Error reordering ConsolePlugins", err)
+		return
+	}
+	oldByName := make(map[string]*plugin.ConsolePlugin, len(oldPlugins))
+	for i := range oldPlugins {
+		oldByName[oldPlugins[i].Name] = &oldPlugins[i]
+	}
+	for i := range consolePlugins {
+		if old, ok := oldByName[consolePlugins[i].Name]; ok {
+			h.recordSpecChange(request, old, &consolePlugins[i], plugin.ChangeReordered)
+		}
+	}
+
+	langs := preferredLanguages(request)
+	consolePluginsTrimmed := make([]ConsolePluginTrimmed, 0, len(consolePlugins))
+	for i := range consolePlugins {
+		consolePluginsTrimmed = append(consolePluginsTrimmed, h.newConsolePluginTrimmed(&consolePlugins[i], langs))
+	}
+	zlog.Infof("Successfully reordered %d ConsolePlugins", len(consolePlugins))
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: consolePluginsTrimmed,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// bulkEnablementBody sets the enablement of either the listed ConsolePlugins, or all the ConsolePlugins
+// matching the label selector
+type bulkEnablementBody struct {
+	Items         []plugin.EnablementItem `json:"items,omitempty"`
+	LabelSelector string                  `json:"labelSelector,omitempty"`
+	Enabled       *bool                   `json:"enabled,omitempty"`
+}
+
+// enablementItems resolves the body into the expected enablement of every ConsolePlugin
+func (h *Handler) enablementItems(body *bulkEnablementBody) ([]plugin.EnablementItem, error) {
+	if (len(body.Items) > 0) == (body.LabelSelector != "") {
+		return nil, apierrors.NewBadRequest("exactly one of items and labelSelector is required")
+	}
+	if len(body.Items) > 0 {
+		return body.Items, nil
+	}
+	if body.Enabled == nil {
+		return nil, apierrors.NewBadRequest("enabled is required with labelSelector")
+	}
+	selector, err := labels.Parse(body.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid labelSelector: %v", err))
+	}
+	consolePlugins, err := h.manager.ListConsolePluginsBySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]plugin.EnablementItem, 0, len(consolePlugins))
+	for _, cp := range consolePlugins {
+		items = append(items, plugin.EnablementItem{PluginName: cp.Name, Enabled: *body.Enabled})
+	}
+	return items, nil
+}
+
+func (h *Handler) setEnablementBulk(request *restful.Request, response *restful.Response) {
+	body := &bulkEnablementBody{}
+	if err := json.NewDecoder(request.Request.Body).Decode(body); err != nil {
+		zlog.Errorf("Error parsing request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+	items, err := h.enablementItems(body)
+	if err != nil {
+		writeManagerError(response, "Error resolving ConsolePlugins", err)
+		return
+	}
+
+	previous := h.enablementSnapshot()
+	results := h.manager.SetPluginEnablementBulk(request.Request.Context(), items, plugin.DefaultBulkWorkers)
+	counts := map[plugin.EnablementResultStatus]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	for _, result := range results {
+		if result.Status == plugin.EnablementChanged {
+			h.audit.Record(request.Request.Context(), newEnablementChange(request, result.PluginName, previous,
+				result.Enabled))
+		}
+	}
+	zlog.Infof("Set enablement of %d ConsolePlugins in bulk: %v", len(results), counts)
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg: fmt.Sprintf("%d changed, %d unchanged, %d not found, %d failed",
+			counts[plugin.EnablementChanged], counts[plugin.EnablementUnchanged],
+			counts[plugin.EnablementNotFound], counts[plugin.EnablementError]),
+		Data: results,
 	}
 	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
 }
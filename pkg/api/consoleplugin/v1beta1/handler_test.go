@@ -13,7 +13,9 @@
 package v1beta1
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,12 +23,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/rest"
 
@@ -34,12 +41,14 @@ import (
 	"plugin-management-service/pkg/plugin"
 	"plugin-management-service/pkg/server/runtime"
 	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/utils/testutil"
 )
 
 func TestBindPluginRoute(t *testing.T) {
 	type args struct {
 		webService *restful.WebService
 		kubeConfig *rest.Config
+		manager    *plugin.ConsolePluginManager
 	}
 	tests := []struct {
 		name     string
@@ -51,17 +60,40 @@ func TestBindPluginRoute(t *testing.T) {
 			args{
 				webService: runtime.GetPluginWebService(),
 				kubeConfig: &rest.Config{},
+				manager:    newTestPluginManager(),
 			},
 			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			BindPluginRoute(tt.args.webService, tt.args.kubeConfig)
+			BindPluginRoute(tt.args.webService, tt.args.kubeConfig, tt.args.manager, 20<<20)
 		})
 	}
 }
 
+// TestCollectionRouteNamesReserved checks that no ConsolePlugin could be named after a collection route, which
+// would shadow /consoleplugins/{pluginName}
+func TestCollectionRouteNamesReserved(t *testing.T) {
+	ws := &restful.WebService{}
+	BindPluginRoute(ws, &rest.Config{}, newTestPluginManager(), 20<<20)
+	for _, route := range ws.Routes() {
+		_, subPath, _ := strings.Cut(route.Path, "/consoleplugins/")
+		name, _, _ := strings.Cut(subPath, "/")
+		if name == "" || strings.HasPrefix(name, "{") {
+			continue
+		}
+		cp := &plugin.ConsolePlugin{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		reserved := false
+		for _, err := range plugin.ValidateConsolePlugin(cp) {
+			reserved = reserved || err.Field == "metadata.name"
+		}
+		if !reserved {
+			t.Errorf("ConsolePlugin name %q of route %s %s is not reserved", name, route.Method, route.Path)
+		}
+	}
+}
+
 var testConsolePluginUnstructured = unstructured.Unstructured{
 	Object: map[string]interface{}{
 		"apiVersion": "console.openfuyao.com/v1beta1",
@@ -69,7 +101,8 @@ var testConsolePluginUnstructured = unstructured.Unstructured{
 		"metadata": map[string]interface{}{
 			"name": "test-consoleplugin",
 			"annotations": map[string]interface{}{
-				"meta.helm.sh/release-name": "test-release",
+				"meta.helm.sh/release-name":      "test-release",
+				"meta.helm.sh/release-namespace": "test-release-ns",
 			},
 		},
 		"spec": map[string]interface{}{
@@ -129,14 +162,17 @@ var dummyConsolePluginTrimmed = ConsolePluginTrimmed{
 }
 
 func newFakeDynamicClientSet() *dynamicfake.FakeDynamicClient {
+	return newFakeDynamicClient(&testConsolePluginUnstructured, &dummyConsolePluginUnstructured)
+}
+
+func newFakeDynamicClient(objects ...k8sruntime.Object) *dynamicfake.FakeDynamicClient {
 	scheme := k8sruntime.NewScheme()
 	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
 		scheme,
 		map[schema.GroupVersionResource]string{
 			{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
 		},
-		&testConsolePluginUnstructured,
-		&dummyConsolePluginUnstructured,
+		objects...,
 	)
 }
 
@@ -158,10 +194,35 @@ func testBindPluginRoute(webService *restful.WebService) {
 	webService.Route(webService.GET("/consoleplugins/").
 		To(handler.listConsolePlugins))
 
+	webService.Route(webService.GET("/consoleplugins/events").
+		To(handler.watchConsolePlugins))
+
 	webService.Route(webService.GET("/consoleplugins/{pluginName}").
 		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
 		To(handler.getConsolePlugin))
 
+	webService.Route(webService.PUT("/consoleplugins/order").
+		To(handler.reorderConsolePlugins))
+
+	webService.Route(webService.POST("/consoleplugins/enablement").
+		To(handler.setEnablementBulk))
+
+	webService.Route(webService.POST("/consoleplugins/").
+		To(handler.createConsolePlugin))
+
+	webService.Route(webService.PUT("/consoleplugins/{pluginName}").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		To(handler.updateConsolePlugin))
+
+	webService.Route(webService.PATCH("/consoleplugins/{pluginName}").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Consumes("application/merge-patch+json", restful.MIME_JSON).
+		To(handler.patchConsolePlugin))
+
+	webService.Route(webService.DELETE("/consoleplugins/{pluginName}").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		To(handler.deleteConsolePlugin))
+
 	webService.Route(webService.GET("/consoleplugins/{pluginName}/enabled").
 		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
 		To(handler.checkEnablement))
@@ -357,6 +418,408 @@ func TestHandlerListConsolePlugins(t *testing.T) {
 	})
 }
 
+func TestHandlerListConsolePluginsWithOptions(t *testing.T) {
+	c := initTestContainer()
+	tests := []struct {
+		name         string
+		query        string
+		wantCode     int
+		wantPlugins  []string
+		wantContinue bool
+	}{
+		{"TestEnabled", "enabled=false", http.StatusOK, []string{"dummy-consoleplugin"}, false},
+		{"TestRelease", "release=test-release", http.StatusOK, []string{"test-consoleplugin"}, false},
+		{"TestSearch", "search=dummy", http.StatusOK, []string{"dummy-consoleplugin"}, false},
+		{"TestSortByDisplayName", "sortBy=displayName", http.StatusOK,
+			[]string{"dummy-consoleplugin", "test-consoleplugin"}, false},
+		{"TestLimit", "sortBy=displayName&limit=1", http.StatusOK, []string{"dummy-consoleplugin"}, true},
+		{"TestInvalidEnabled", "enabled=maybe", http.StatusBadRequest, nil, false},
+		{"TestInvalidLimit", "limit=-1", http.StatusBadRequest, nil, false},
+		{"TestInvalidLabelSelector", "labelSelector=a%20in%20(", http.StatusBadRequest, nil, false},
+		{"TestInvalidSortBy", "sortBy=version", http.StatusBadRequest, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins?"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d", resp.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resultData []ConsolePluginTrimmed
+			if err = parseResponseData(result, &resultData); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, res := range resultData {
+				got = append(got, res.PluginName)
+			}
+			if !reflect.DeepEqual(got, tt.wantPlugins) {
+				t.Errorf("plugins = %v, want %v", got, tt.wantPlugins)
+			}
+			if token := resp.Header().Get(constant.ContinueHeader); (token != "") != tt.wantContinue {
+				t.Errorf("continue header = %q, want continue %v", token, tt.wantContinue)
+			}
+		})
+	}
+
+	t.Run("TestContinue", func(t *testing.T) {
+		url := "http://example.com/rest/plugin-management/v1beta1/consoleplugins?sortBy=displayName&limit=1"
+		resp := httptest.NewRecorder()
+		c.Dispatch(resp, httptest.NewRequest("GET", url, nil))
+		token := resp.Header().Get(constant.ContinueHeader)
+		if resp.Header().Get(constant.RemainingItemCountHeader) != "1" {
+			t.Errorf("remaining item count header = %q, want 1", resp.Header().Get(constant.RemainingItemCountHeader))
+		}
+
+		resp = httptest.NewRecorder()
+		c.Dispatch(resp, httptest.NewRequest("GET", url+"&continue="+token, nil))
+		result, err := parseResponseJSON(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resultData []ConsolePluginTrimmed
+		if err = parseResponseData(result, &resultData); err != nil {
+			t.Fatal(err)
+		}
+		if len(resultData) != 1 || resultData[0].PluginName != "test-consoleplugin" {
+			t.Errorf("second page = %v, want test-consoleplugin", resultData)
+		}
+		if resp.Header().Get(constant.ContinueHeader) != "" {
+			t.Errorf("last page should not have a continue token")
+		}
+	})
+}
+
+func TestHandlerListIncompatibleConsolePlugins(t *testing.T) {
+	incompatible := testConsolePluginUnstructured.DeepCopy()
+	if err := unstructured.SetNestedField(incompatible.Object, "<2.0", "spec", "consoleVersion"); err != nil {
+		t.Fatal(err)
+	}
+	manager := &plugin.ConsolePluginManager{
+		Client: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			k8sruntime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
+			},
+			incompatible,
+		),
+		ConsoleVersion: version.MustParseGeneric("2.0.0"),
+	}
+	handler := newHandler(&rest.Config{}, manager, 20<<20)
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/").To(handler.listConsolePlugins))
+	c := restful.NewContainer()
+	c.Add(ws)
+
+	req := httptest.NewRequest("GET", "http://example.com/rest/plugin-management/v1beta1/consoleplugins/", nil)
+	resp := httptest.NewRecorder()
+	c.Dispatch(resp, req)
+
+	result, err := parseResponseJSON(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resultData []ConsolePluginTrimmed
+	if err = parseResponseData(result, &resultData); err != nil {
+		t.Fatal(err)
+	}
+	if len(resultData) != 1 || resultData[0].Enabled || resultData[0].Reason != plugin.ReasonIncompatible {
+		t.Errorf("incompatible ConsolePlugin should be marked and disabled: %+v", resultData)
+	}
+}
+
+func TestHandlerLocalizedConsolePlugins(t *testing.T) {
+	localized := testConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedStringMap(localized.Object, map[string]string{"en": "Test Extension"},
+		"spec", "displayNames")
+	manager := &plugin.ConsolePluginManager{
+		Client: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			k8sruntime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
+			},
+			localized,
+		),
+	}
+	handler := newHandler(&rest.Config{}, manager, 20<<20)
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/").To(handler.listConsolePlugins))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}").To(handler.getConsolePlugin))
+	c := restful.NewContainer()
+	c.Add(ws)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptLanguage string
+		want           string
+	}{
+		{"TestListDefault", "consoleplugins/", "", "测试扩展"},
+		{"TestListAcceptLanguage", "consoleplugins/", "en-US,en;q=0.9", "Test Extension"},
+		{"TestListUnmatched", "consoleplugins/", "fr", "测试扩展"},
+		{"TestGetLang", "consoleplugins/test-consoleplugin?lang=en", "zh-CN", "Test Extension"},
+		{"TestGetAcceptLanguage", "consoleplugins/test-consoleplugin", "zh-CN", "测试扩展"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/rest/plugin-management/v1beta1/"+tt.path, nil)
+			req.Header.Set(constant.AcceptLanguageHeader, tt.acceptLanguage)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if !strings.Contains(resp.Header().Get(constant.VaryHeader), constant.AcceptLanguageHeader) {
+				t.Errorf("response should vary by %s", constant.AcceptLanguageHeader)
+			}
+
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got ConsolePluginTrimmed
+			if strings.HasPrefix(tt.path, "consoleplugins/test-consoleplugin") {
+				err = parseResponseData(result, &got)
+			} else {
+				var list []ConsolePluginTrimmed
+				err = parseResponseData(result, &list)
+				if len(list) == 1 {
+					got = list[0]
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.DisplayName != tt.want {
+				t.Errorf("displayName = %q, want %q", got.DisplayName, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerConsolePluginVisibility(t *testing.T) {
+	adminOnly := testConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedSlice(adminOnly.Object, []interface{}{
+		map[string]interface{}{"verb": "get", "group": "cluster.openfuyao.com", "resource": "clusters"},
+	}, "spec", "requiredAccess")
+	public := dummyConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedSlice(public.Object, []interface{}{
+		map[string]interface{}{"pageName": "overview", "displayName": "Overview"},
+		map[string]interface{}{"pageName": "secrets", "displayName": "Secrets", "requiredAccess": []interface{}{
+			map[string]interface{}{"verb": "list", "resource": "secrets"},
+		}},
+	}, "spec", "subPages")
+	manager := &plugin.ConsolePluginManager{
+		Client: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			k8sruntime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
+			},
+			adminOnly, public,
+		),
+	}
+	kubeClient := testutil.NewSubjectAccessReviewClient(nil, func(review *authorizationv1.SubjectAccessReview) error {
+		review.Status.Allowed = review.Spec.User == "admin"
+		return nil
+	})
+	handler := newHandler(&rest.Config{}, manager, 20<<20)
+	handler.visibility = &plugin.VisibilityFilter{
+		Reviewer: plugin.NewSubjectAccessReviewer(kubeClient, plugin.DefaultAccessReviewTTL),
+	}
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/").To(handler.listConsolePlugins))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}").To(handler.getConsolePlugin))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/proxy/{path:*}").To(handler.proxyConsolePlugin))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/manifest").To(handler.getConsolePluginManifest))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/icon").To(handler.getConsolePluginIcon))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/release").To(handler.getConsolePluginRelease))
+	c := restful.NewContainer()
+	c.Add(ws)
+	c.Filter(withTestUser)
+
+	tests := []struct {
+		name        string
+		user        string
+		wantPlugins string
+		wantGetCode int
+	}{
+		{"TestAdmin", "admin", "dummy-consoleplugin[overview secrets],test-consoleplugin", http.StatusOK},
+		{"TestUser", "user", "dummy-consoleplugin[overview]", http.StatusNotFound},
+		{"TestAnonymous", "", "dummy-consoleplugin[overview]", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/rest/plugin-management/v1beta1/consoleplugins", nil)
+			req.Header.Set("X-Test-User", tt.user)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resultData []ConsolePluginTrimmed
+			if err = parseResponseData(result, &resultData); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, res := range resultData {
+				item := res.PluginName
+				if len(res.SubPages) > 0 {
+					var pages []string
+					for _, page := range res.SubPages {
+						pages = append(pages, page.PageName)
+					}
+					item += "[" + strings.Join(pages, " ") + "]"
+				}
+				got = append(got, item)
+			}
+			if strings.Join(got, ",") != tt.wantPlugins {
+				t.Errorf("plugins = %s, want %s", strings.Join(got, ","), tt.wantPlugins)
+			}
+
+			req = httptest.NewRequest("GET",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins/test-consoleplugin", nil)
+			req.Header.Set("X-Test-User", tt.user)
+			resp = httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantGetCode {
+				t.Errorf("get status = %d, want %d", resp.Code, tt.wantGetCode)
+			}
+			if tt.wantGetCode != http.StatusNotFound {
+				return
+			}
+			for _, sub := range []string{"proxy/main.js", "manifest", "icon", "release"} {
+				req = httptest.NewRequest("GET",
+					"http://example.com/rest/plugin-management/v1beta1/consoleplugins/test-consoleplugin/"+sub, nil)
+				req.Header.Set("X-Test-User", tt.user)
+				resp = httptest.NewRecorder()
+				c.Dispatch(resp, req)
+				if resp.Code != http.StatusNotFound {
+					t.Errorf("%s status = %d, want 404", sub, resp.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerReorderConsolePlugins(t *testing.T) {
+	tests := []struct {
+		name      string
+		reqBody   string
+		wantCode  int32
+		wantOrder []string
+	}{
+		{"TestInvalidBody", "{", constant.ClientError, nil},
+		{"TestNotFound", `{"pluginNames": ["not-installed"]}`, constant.ClientError, nil},
+		{"TestReorder", `{"pluginNames": ["test-consoleplugin", "dummy-consoleplugin"]}`, constant.Success,
+			[]string{"test-consoleplugin:0", "dummy-consoleplugin:1"}},
+		{"TestReorderPartial", `{"pluginNames": ["test-consoleplugin"]}`, constant.Success,
+			[]string{"test-consoleplugin:0", "dummy-consoleplugin:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initTestContainer()
+			req := httptest.NewRequest("PUT", "http://example.com/rest/plugin-management/v1beta1/consoleplugins/order",
+				strings.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", result.Code, tt.wantCode, result.Msg)
+			}
+			if tt.wantOrder == nil {
+				return
+			}
+			var resultData []ConsolePluginTrimmed
+			if err = parseResponseData(result, &resultData); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, cp := range resultData {
+				got = append(got, cp.PluginName+":"+*cp.Order)
+			}
+			if !reflect.DeepEqual(got, tt.wantOrder) {
+				t.Errorf("menu order = %v, want %v", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestHandlerSetEnablementBulk(t *testing.T) {
+	tests := []struct {
+		name        string
+		reqBody     string
+		wantCode    int32
+		wantResults []plugin.EnablementResultStatus
+	}{
+		{"TestInvalidBody", "{", constant.ClientError, nil},
+		{"TestNeitherItemsNorSelector", `{}`, constant.ClientError, nil},
+		{"TestSelectorWithoutEnabled", `{"labelSelector": "a=b"}`, constant.ClientError, nil},
+		{"TestInvalidSelector", `{"labelSelector": "a=(", "enabled": true}`, constant.ClientError, nil},
+		{
+			"TestItems",
+			`{"items": [{"pluginName": "test-consoleplugin", "enabled": false},
+				{"pluginName": "dummy-consoleplugin", "enabled": false},
+				{"pluginName": "not-installed", "enabled": true}]}`,
+			constant.Success,
+			[]plugin.EnablementResultStatus{plugin.EnablementChanged, plugin.EnablementUnchanged,
+				plugin.EnablementNotFound},
+		},
+		{
+			"TestSelector",
+			`{"labelSelector": "!category", "enabled": true}`,
+			constant.Success,
+			[]plugin.EnablementResultStatus{plugin.EnablementChanged, plugin.EnablementUnchanged},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initTestContainer()
+			req := httptest.NewRequest("POST",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins/enablement", strings.NewReader(tt.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", result.Code, tt.wantCode, result.Msg)
+			}
+			if tt.wantResults == nil {
+				return
+			}
+			var results []plugin.EnablementResult
+			if err = parseResponseData(result, &results); err != nil {
+				t.Fatal(err)
+			}
+			var got []plugin.EnablementResultStatus
+			for _, r := range results {
+				got = append(got, r.Status)
+			}
+			if !reflect.DeepEqual(got, tt.wantResults) {
+				t.Errorf("results = %v, want %v", got, tt.wantResults)
+			}
+		})
+	}
+}
+
 func TestHandlerSetEnablement(t *testing.T) {
 	c := initTestContainer()
 	tests := []struct {
@@ -420,6 +883,337 @@ func TestHandlerSetEnablement(t *testing.T) {
 	}
 }
 
+func TestHandlerSetEnablementPrecondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  string
+		reqBody  string
+		wantCode int32
+	}{
+		{"TestBodyMismatch", "", `{"pluginName": "test-consoleplugin", "enabled": false, "resourceVersion": "1"}`,
+			constant.Conflict},
+		{"TestIfMatchMismatch", `"1"`, `{"pluginName": "test-consoleplugin", "enabled": false}`, constant.Conflict},
+		{"TestIfMatchAny", "*", `{"pluginName": "test-consoleplugin", "enabled": false}`, constant.Success},
+		{"TestIfMatchDisagree", `"1"`, `{"pluginName": "test-consoleplugin", "enabled": false, "resourceVersion": "2"}`,
+			constant.ClientError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initTestContainer()
+			req := httptest.NewRequest("POST",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins/test-consoleplugin/enabled",
+				bytes.NewBufferString(tt.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set(constant.IfMatchHeader, tt.ifMatch)
+			}
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d: %s", result.Code, tt.wantCode, result.Msg)
+			}
+		})
+	}
+}
+
+const validSpecBody = `{
+	"pluginName": "%s",
+	"displayName": "New Plugin",
+	"entrypoint": "Side",
+	"subPages": [{"pageName": "page", "displayName": "Page"}],
+	"backend": {"type": "Service", "service": {"name": "new-svc", "namespace": "new-ns"}}
+}`
+
+func TestHandlerModifyConsolePlugin(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		pluginName  string
+		contentType string
+		reqBody     string
+		wantCode    int32
+		wantFields  []string
+	}{
+		{
+			"TestCreateInvalidBody",
+			"POST",
+			"",
+			restful.MIME_JSON,
+			"{",
+			constant.ClientError,
+			nil,
+		},
+		{
+			"TestCreateValidationFailed",
+			"POST",
+			"",
+			restful.MIME_JSON,
+			`{"pluginName": "new-plugin", "entrypoint": "Nav", "subPages": [{"pageName": "p", "displayName": "P"}]}`,
+			constant.ClientError,
+			[]string{"spec.displayName", "spec.subPages", "spec.backend"},
+		},
+		{
+			"TestCreateAlreadyExists",
+			"POST",
+			"",
+			restful.MIME_JSON,
+			fmt.Sprintf(validSpecBody, "test-consoleplugin"),
+			constant.Conflict,
+			nil,
+		},
+		{
+			"TestCreate",
+			"POST",
+			"",
+			restful.MIME_JSON,
+			fmt.Sprintf(validSpecBody, "new-plugin"),
+			constant.FileCreated,
+			nil,
+		},
+		{
+			"TestUpdateNonMatchPluginName",
+			"PUT",
+			"test-consoleplugin",
+			restful.MIME_JSON,
+			fmt.Sprintf(validSpecBody, "new-plugin"),
+			constant.ClientError,
+			nil,
+		},
+		{
+			"TestUpdateNotFound",
+			"PUT",
+			"not-installed",
+			restful.MIME_JSON,
+			fmt.Sprintf(validSpecBody, "not-installed"),
+			constant.ResourceNotFound,
+			nil,
+		},
+		{
+			"TestUpdate",
+			"PUT",
+			"test-consoleplugin",
+			restful.MIME_JSON,
+			fmt.Sprintf(validSpecBody, "test-consoleplugin"),
+			constant.Success,
+			nil,
+		},
+		{
+			"TestPatchValidationFailed",
+			"PATCH",
+			"dummy-consoleplugin",
+			"application/merge-patch+json",
+			`{"displayName": ""}`,
+			constant.ClientError,
+			[]string{"spec.displayName", "spec.entrypoint"},
+		},
+		{
+			"TestPatchUnknownField",
+			"PATCH",
+			"dummy-consoleplugin",
+			"application/merge-patch+json",
+			`{"unknown": true}`,
+			constant.ClientError,
+			nil,
+		},
+		{
+			"TestPatch",
+			"PATCH",
+			"dummy-consoleplugin",
+			"application/merge-patch+json",
+			`{"entrypoint": "Nav", "displayName": "Patched"}`,
+			constant.Success,
+			nil,
+		},
+		{
+			"TestDeleteNotFound",
+			"DELETE",
+			"not-installed",
+			restful.MIME_JSON,
+			"",
+			constant.ResourceNotFound,
+			nil,
+		},
+		{
+			"TestDelete",
+			"DELETE",
+			"dummy-consoleplugin",
+			restful.MIME_JSON,
+			"",
+			constant.Success,
+			nil,
+		},
+	}
+
+	c := initTestContainer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(
+				tt.method,
+				fmt.Sprintf("http://example.com/rest/plugin-management/v1beta1/consoleplugins/%s", tt.pluginName),
+				strings.NewReader(tt.reqBody),
+			)
+			req.Header.Set("Content-Type", tt.contentType)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			if result.Code != tt.wantCode {
+				t.Errorf("%s consoleplugin %s want status code %d, but get %d: %s",
+					tt.method, tt.pluginName, tt.wantCode, result.Code, result.Msg)
+				return
+			}
+			if tt.wantFields == nil {
+				return
+			}
+			var fieldErrs []FieldError
+			if err = parseResponseData(result, &fieldErrs); err != nil {
+				t.Error(err.Error())
+			}
+			var gotFields []string
+			for _, fieldErr := range fieldErrs {
+				gotFields = append(gotFields, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(gotFields, tt.wantFields) {
+				t.Errorf("%s consoleplugin field errors = %v, want %v", tt.method, gotFields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestHandlerWatchConsolePlugins(t *testing.T) {
+	client := newFakeDynamicClientSet()
+	handler := newHandler(&rest.Config{}, &plugin.ConsolePluginManager{Client: client}, 20<<20)
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/events").Produces(mimeEventStream).To(handler.watchConsolePlugins))
+	container := restful.NewContainer()
+	container.Add(ws)
+	server := httptest.NewServer(container)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		server.URL+"/rest/plugin-management/v1beta1/consoleplugins/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", mimeEventStream)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != mimeEventStream {
+		t.Fatalf("Content-Type = %s, want %s", ct, mimeEventStream)
+	}
+
+	newPlugin := dummyConsolePluginUnstructured.DeepCopy()
+	newPlugin.SetName("new-consoleplugin")
+	_, err = client.Resource(schema.GroupVersionResource{
+		Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins",
+	}).Create(ctx, newPlugin, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventType, event := readConsolePluginEvent(t, bufio.NewScanner(resp.Body))
+	if eventType != "ADDED" || event.Type != "ADDED" {
+		t.Errorf("event type = %s, want ADDED", eventType)
+	}
+	if event.Object.PluginName != "dummy-consoleplugin" {
+		t.Errorf("event object pluginName = %s, want dummy-consoleplugin", event.Object.PluginName)
+	}
+}
+
+// readConsolePluginEvent reads the next ConsolePlugin event of the stream and its SSE event type
+func readConsolePluginEvent(t *testing.T, scanner *bufio.Scanner) (string, ConsolePluginEvent) {
+	var eventType string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			eventType = strings.TrimPrefix(line, "event: ")
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event ConsolePluginEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatal(err)
+		}
+		return eventType, event
+	}
+	t.Fatalf("no event received: %v", scanner.Err())
+	return "", ConsolePluginEvent{}
+}
+
+func TestHandlerWatchConsolePluginsHidden(t *testing.T) {
+	client := newFakeDynamicClientSet()
+	handler := newHandler(&rest.Config{}, &plugin.ConsolePluginManager{Client: client}, 20<<20)
+	handler.visibility = &plugin.VisibilityFilter{Reviewer: &fakeAccessReviewer{}}
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/events").Produces(mimeEventStream).To(handler.watchConsolePlugins))
+	container := restful.NewContainer()
+	container.Add(ws)
+	container.Filter(withTestUser)
+	server := httptest.NewServer(container)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		server.URL+"/rest/plugin-management/v1beta1/consoleplugins/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", mimeEventStream)
+	req.Header.Set("X-Test-User", "viewer")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// a ConsolePlugin never visible to the user is skipped, one becoming hidden is deleted from the client
+	requiredAccess := []interface{}{map[string]interface{}{"verb": "get", "resource": "secrets"}}
+	resource := client.Resource(schema.GroupVersionResource{
+		Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins",
+	})
+	hidden := dummyConsolePluginUnstructured.DeepCopy()
+	hidden.SetName("hidden-consoleplugin")
+	if err = unstructured.SetNestedSlice(hidden.Object, requiredAccess, "spec", "requiredAccess"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = resource.Create(ctx, hidden, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	narrowed := testConsolePluginUnstructured.DeepCopy()
+	if err = unstructured.SetNestedSlice(narrowed.Object, requiredAccess, "spec", "requiredAccess"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = resource.Update(ctx, narrowed, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	eventType, event := readConsolePluginEvent(t, bufio.NewScanner(resp.Body))
+	if eventType != "DELETED" || event.Type != "DELETED" {
+		t.Errorf("event type = %s, want DELETED", eventType)
+	}
+	want := ConsolePluginTrimmed{PluginName: "test-consoleplugin"}
+	if !reflect.DeepEqual(event.Object, want) {
+		t.Errorf("event object = %+v, want %+v", event.Object, want)
+	}
+}
+
 func TestFormatOrder(t *testing.T) {
 	testInt := int64(123456)
 	testStr := "123456"
@@ -457,25 +1251,25 @@ func TestFormatOrder(t *testing.T) {
 
 func TestNewHandler(t *testing.T) {
 	type args struct {
-		config *rest.Config
+		config  *rest.Config
+		manager *plugin.ConsolePluginManager
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name string
+		args args
 	}{
 		{"TestNewHandler",
 			args{
 				&rest.Config{},
+				newTestPluginManager(),
 			},
-			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := newHandler(tt.args.config)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("newHandler() error = %v, wantErr %v", err, tt.wantErr)
+			h := newHandler(tt.args.config, tt.args.manager, 20<<20)
+			if h.manager != tt.args.manager {
+				t.Errorf("newHandler() manager = %v, want %v", h.manager, tt.args.manager)
 				return
 			}
 		})
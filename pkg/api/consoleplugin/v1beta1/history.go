@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// newChangeRecord returns the record of a change of the ConsolePlugin made by the request
+func newChangeRecord(request *restful.Request, pluginName string, action plugin.ChangeAction) *plugin.ChangeRecord {
+	actor := auth.Anonymous
+	if user, ok := auth.UserFrom(request.Request.Context()); ok {
+		actor = user.Username
+	}
+	sourceIP, _, err := net.SplitHostPort(request.Request.RemoteAddr)
+	if err != nil {
+		sourceIP = request.Request.RemoteAddr
+	}
+	return &plugin.ChangeRecord{
+		PluginName:   pluginName,
+		Action:       action,
+		Actor:        actor,
+		SourceIP:     sourceIP,
+		ForwardedFor: request.HeaderParameter("X-Forwarded-For"),
+	}
+}
+
+// newEnablementChange returns the record of a change of the enablement, the previous value being looked up in the
+// snapshot taken before the change
+func newEnablementChange(request *restful.Request, pluginName string, previous map[string]bool,
+	enabled bool) *plugin.ChangeRecord {
+	action := plugin.ChangeDisabled
+	if enabled {
+		action = plugin.ChangeEnabled
+	}
+	record := newChangeRecord(request, pluginName, action)
+	record.Fields = []string{"spec.enabled"}
+	if old, ok := previous[pluginName]; ok {
+		record.OldValue = strconv.FormatBool(old)
+	}
+	record.NewValue = strconv.FormatBool(enabled)
+	return record
+}
+
+// enablementSnapshot returns the enablement of every ConsolePlugin before a change, so the previous values are
+// recorded as they were read. It is empty if the ConsolePlugins could not be listed.
+func (h *Handler) enablementSnapshot() map[string]bool {
+	consolePlugins, err := h.manager.ListConsolePlugins()
+	if err != nil {
+		zlog.Warnf("Error listing ConsolePlugins, the previous enablement is not recorded: %v", err)
+		return nil
+	}
+	snapshot := make(map[string]bool, len(consolePlugins))
+	for i := range consolePlugins {
+		snapshot[consolePlugins[i].Name] = consolePlugins[i].Spec.Enabled
+	}
+	return snapshot
+}
+
+// recordSpecChange records the changed fields of the spec, nothing if the spec is unchanged. The change is
+// recorded without fields if the ConsolePlugin could not be read before.
+func (h *Handler) recordSpecChange(request *restful.Request, old, updated *plugin.ConsolePlugin,
+	action plugin.ChangeAction) {
+	record := newChangeRecord(request, updated.Name, action)
+	if old != nil {
+		record.Fields, record.OldValue, record.NewValue = plugin.DiffConsolePluginSpec(&old.Spec, &updated.Spec)
+		if len(record.Fields) == 0 {
+			return
+		}
+	}
+	h.audit.Record(request.Request.Context(), record)
+}
+
+// getConsolePluginHistory returns the recent changes of the ConsolePlugin, the latest first
+func (h *Handler) getConsolePluginHistory(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+	limit := plugin.DefaultHistoryLimit
+	if limitParam := request.QueryParameter(constant.Limit); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > plugin.MaxHistoryLimit {
+			writeManagerError(response, "Invalid limit", apierrors.NewBadRequest(
+				fmt.Sprintf("limit must be an integer between 1 and %d", plugin.MaxHistoryLimit)))
+			return
+		}
+	}
+	if h.audit == nil {
+		respJson := &httputil.ResponseJson{
+			Code: constant.ServiceUnavailable,
+			Msg:  "history of ConsolePlugins is unavailable",
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+		return
+	}
+
+	// the history of a deleted ConsolePlugin is kept, but that of one hidden from the user is not revealed
+	consolePlugin, err := h.manager.GetConsolePlugin(pluginName)
+	if writeCacheNotSynced(response, err) {
+		return
+	}
+	if err == nil {
+		ctx := request.Request.Context()
+		user, _ := auth.UserFrom(ctx)
+		if _, visible := h.visibility.FilterOne(ctx, user, consolePlugin); !visible {
+			respJson := &httputil.ResponseJson{
+				Code: constant.ResourceNotFound,
+				Msg:  fmt.Sprintf("Error getting ConsolePlugin history: %s not found", pluginName),
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusNotFound, respJson)
+			return
+		}
+	}
+
+	records, err := h.audit.History(request.Request.Context(), pluginName, limit)
+	if err != nil {
+		writeManagerError(response, "Error listing ConsolePlugin history", err)
+		return
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: records,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
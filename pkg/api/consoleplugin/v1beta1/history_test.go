@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+)
+
+func TestHandlerConsolePluginHistory(t *testing.T) {
+	handler := newTestHandler()
+	handler.audit = plugin.NewAuditRecorder(kubefake.NewSimpleClientset())
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/consoleplugins/{pluginName}/enabled").To(handler.setEnablement))
+	ws.Route(ws.PATCH("/consoleplugins/{pluginName}").
+		Consumes("application/merge-patch+json", restful.MIME_JSON).
+		To(handler.patchConsolePlugin))
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/history").To(handler.getConsolePluginHistory))
+	c := restful.NewContainer()
+	c.Add(ws)
+	c.Filter(withTestUser)
+
+	baseURL := "http://example.com/rest/plugin-management/v1beta1/consoleplugins/test-consoleplugin"
+	mutations := []struct {
+		method      string
+		url         string
+		contentType string
+		body        string
+		user        string
+	}{
+		{"POST", baseURL + "/enabled", "application/json",
+			`{"pluginName": "test-consoleplugin", "enabled": false}`, "admin"},
+		// unchanged, not recorded
+		{"POST", baseURL + "/enabled", "application/json",
+			`{"pluginName": "test-consoleplugin", "enabled": false}`, "admin"},
+		{"PATCH", baseURL, "application/merge-patch+json", `{"displayName": "Renamed", "entrypoint": "Nav"}`, "operator"},
+	}
+	for _, m := range mutations {
+		req := httptest.NewRequest(m.method, m.url, bytes.NewBufferString(m.body))
+		req.Header.Set("Content-Type", m.contentType)
+		req.Header.Set("X-Test-User", m.user)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		resp := httptest.NewRecorder()
+		c.Dispatch(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s %s status = %d, body %s", m.method, m.url, resp.Code, resp.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     string
+	}{
+		{"TestHistory", "", http.StatusOK,
+			"operator Updated spec.backend,spec.displayName,spec.entrypoint,spec.order;admin Disabled spec.enabled true->false"},
+		{"TestLimit", "?limit=1", http.StatusOK, "operator Updated spec.backend,spec.displayName,spec.entrypoint,spec.order"},
+		{"TestInvalidLimit", "?limit=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", baseURL+"/history"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", resp.Code, tt.wantCode)
+			}
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != http.StatusOK {
+				if result.Code != constant.ClientError {
+					t.Errorf("code = %d, want %d", result.Code, constant.ClientError)
+				}
+				return
+			}
+			var records []plugin.ChangeRecord
+			if err = parseResponseData(result, &records); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, record := range records {
+				if record.SourceIP != "192.0.2.1" || record.ForwardedFor != "203.0.113.7" {
+					t.Errorf("record source = %s, %s", record.SourceIP, record.ForwardedFor)
+				}
+				item := record.Actor + " " + string(record.Action) + " " + strings.Join(record.Fields, ",")
+				if record.Action == plugin.ChangeDisabled {
+					item += " " + record.OldValue + "->" + record.NewValue
+				}
+				got = append(got, item)
+			}
+			if strings.Join(got, ";") != tt.want {
+				t.Errorf("history = %s, want %s", strings.Join(got, ";"), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerSetEnablementPartialCascade(t *testing.T) {
+	dependency := testConsolePluginUnstructured.DeepCopy()
+	dependency.SetName("dependency")
+	_ = unstructured.SetNestedField(dependency.Object, "dependency", "spec", "pluginName")
+	_ = unstructured.SetNestedField(dependency.Object, false, "spec", "enabled")
+	dependent := dummyConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedStringSlice(dependent.Object, []string{"dependency"}, "spec", "dependencies")
+	client := newFakeDynamicClient(dependency, dependent)
+	client.PrependReactor("patch", "consoleplugins",
+		func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+			if action.(clienttesting.PatchAction).GetName() == dependent.GetName() {
+				return true, nil, errors.New("etcd is unavailable")
+			}
+			return false, nil, nil
+		})
+	handler := newTestHandler()
+	handler.manager = &plugin.ConsolePluginManager{Client: client}
+	handler.audit = plugin.NewAuditRecorder(kubefake.NewSimpleClientset())
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/consoleplugins/{pluginName}/enabled").To(handler.setEnablement))
+	c := restful.NewContainer()
+	c.Add(ws)
+
+	req := httptest.NewRequest("POST", "http://example.com/rest/plugin-management/v1beta1/consoleplugins/"+
+		dependent.GetName()+"/enabled?cascade=true",
+		bytes.NewBufferString(`{"pluginName": "`+dependent.GetName()+`", "enabled": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	c.Dispatch(resp, req)
+	if resp.Code != http.StatusInternalServerError || !strings.Contains(resp.Body.String(), "dependency") {
+		t.Fatalf("status = %d, body %s, want 500 naming the changed dependency", resp.Code, resp.Body.String())
+	}
+
+	records, err := handler.audit.History(context.Background(), "dependency", plugin.DefaultHistoryLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Action != plugin.ChangeEnabled ||
+		records[0].OldValue != "false" || records[0].NewValue != "true" {
+		t.Errorf("history of the dependency = %+v, want enabled false->true", records)
+	}
+	if records, _ = handler.audit.History(context.Background(), dependent.GetName(), 1); len(records) != 0 {
+		t.Errorf("history of the dependent = %+v, want none", records)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	// iconCacheControl lets browsers reuse the icons for a while before revalidating them with the ETag, while
+	// shared caches must not keep them as the visibility depends on the user
+	iconCacheControl = "private, max-age=300"
+
+	// iconSecurityPolicy keeps anything left in an SVG icon from running when it is opened directly
+	iconSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+)
+
+// getConsolePluginIcon serves the validated icon of the ConsolePlugin
+func (h *Handler) getConsolePluginIcon(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+
+	icon, err := h.icons.Fetch(request.Request.Context(), cp, h.backendURL(cp))
+	if errors.Is(err, plugin.ErrNoIcon) {
+		respJson := &httputil.ResponseJson{
+			Code: constant.ResourceNotFound,
+			Msg:  fmt.Sprintf("ConsolePlugin %s has no icon", pluginName),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusNotFound, respJson)
+		return
+	}
+	if err != nil {
+		zlog.Errorf("Error fetching icon of ConsolePlugin %s: %v", sanitizeLogString(pluginName), err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.BadGateway,
+			Msg:  fmt.Sprintf("Error fetching icon of ConsolePlugin %s: %v", pluginName, err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadGateway, respJson)
+		return
+	}
+
+	etag := strconv.Quote(icon.Digest)
+	header := response.Header()
+	header.Set(constant.ETagHeader, etag)
+	header.Set(constant.CacheControlHeader, iconCacheControl)
+	header.Set(constant.VaryHeader, constant.AuthorizationHeader+", "+constant.CookieHeader)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", iconSecurityPolicy)
+	if ifNoneMatch := request.HeaderParameter(constant.IfNoneMatchHeader); ifNoneMatch != "" &&
+		etagMatches(ifNoneMatch, etag) {
+		response.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Type", icon.ContentType)
+	header.Set("Content-Length", strconv.Itoa(len(icon.Data)))
+	response.WriteHeader(http.StatusOK)
+	if request.Request.Method != http.MethodHead {
+		_, _ = response.Write(icon.Data)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+)
+
+func TestHandlerGetConsolePluginIcon(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	inline := testConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedField(inline.Object, map[string]interface{}{
+		"svg": `<svg onload="alert(1)"><script>alert(2)</script><path d="M0 0"/></svg>`,
+	}, "spec", "icon")
+	remote := dummyConsolePluginUnstructured.DeepCopy()
+	_ = unstructured.SetNestedField(remote.Object, map[string]interface{}{"path": "/icon.png"}, "spec", "icon")
+	noIcon := testConsolePluginUnstructured.DeepCopy()
+	noIcon.SetName("no-icon")
+	hidden := inline.DeepCopy()
+	hidden.SetName("hidden")
+	_ = unstructured.SetNestedSlice(hidden.Object, []interface{}{
+		map[string]interface{}{"verb": "list", "resource": "secrets"},
+	}, "spec", "requiredAccess")
+	manager := &plugin.ConsolePluginManager{
+		Client: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			k8sruntime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "console.openfuyao.com", Version: "v1beta1", Resource: "consoleplugins"}: "ConsolePluginList",
+			},
+			inline, remote, noIcon, hidden,
+		),
+	}
+	handler := newHandler(&rest.Config{}, manager, 20<<20)
+	handler.backendURL = func(cp *plugin.ConsolePlugin) string {
+		return backend.URL
+	}
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		ws.Route(ws.Method(method).Path("/consoleplugins/{pluginName}/icon").
+			Produces(restful.MIME_JSON, "*/*").To(handler.getConsolePluginIcon))
+	}
+	c := restful.NewContainer()
+	c.Add(ws)
+	url := func(name string) string {
+		return "http://example.com/rest/plugin-management/v1beta1/consoleplugins/" + name + "/icon"
+	}
+
+	t.Run("TestInlineSanitized", func(t *testing.T) {
+		req := httptest.NewRequest("GET", url("test-consoleplugin"), nil)
+		req.Header.Set("Accept", "image/avif,image/webp,*/*")
+		resp := httptest.NewRecorder()
+		c.Dispatch(resp, req)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", resp.Code, resp.Body.String())
+		}
+		if got := resp.Header().Get("Content-Type"); got != plugin.MIMETypeSVG {
+			t.Errorf("Content-Type = %q, want %q", got, plugin.MIMETypeSVG)
+		}
+		if got, want := resp.Body.String(), `<svg><path d="M0 0"></path></svg>`; got != want {
+			t.Errorf("icon = %s, want %s", got, want)
+		}
+		etag := resp.Header().Get(constant.ETagHeader)
+		if etag == "" || resp.Header().Get(constant.CacheControlHeader) != iconCacheControl {
+			t.Fatalf("icon should be served with ETag and private Cache-Control")
+		}
+
+		req = httptest.NewRequest("GET", url("test-consoleplugin"), nil)
+		req.Header.Set(constant.IfNoneMatchHeader, etag)
+		resp = httptest.NewRecorder()
+		c.Dispatch(resp, req)
+		if resp.Code != http.StatusNotModified || resp.Body.Len() != 0 {
+			t.Errorf("status = %d with %d bytes, want 304 without body", resp.Code, resp.Body.Len())
+		}
+
+		resp = httptest.NewRecorder()
+		c.Dispatch(resp, httptest.NewRequest("HEAD", url("test-consoleplugin"), nil))
+		if resp.Code != http.StatusOK || resp.Body.Len() != 0 {
+			t.Errorf("HEAD status = %d with %d bytes, want 200 without body", resp.Code, resp.Body.Len())
+		}
+	})
+
+	tests := []struct {
+		name       string
+		pluginName string
+		wantCode   int
+	}{
+		{"TestBackendError", "dummy-consoleplugin", http.StatusBadGateway},
+		{"TestNoIcon", "no-icon", http.StatusNotFound},
+		{"TestPluginNotFound", "not-installed", http.StatusNotFound},
+		{"TestHidden", "hidden", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, httptest.NewRequest("GET", url(tt.pluginName), nil))
+			if resp.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.wantCode, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+type installBody struct {
+	Repo    string `json:"repo"`
+	Chart   string `json:"chart"`
+	Version string `json:"version"`
+
+	// ReleaseName defaults to the chart name, Namespace to openfuyao-system
+	ReleaseName string `json:"releaseName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+
+	// Values are the user-supplied values overriding the chart defaults
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// TimeoutSeconds is how long to wait for the ConsolePlugins to become ready, 10 by default
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+func (b *installBody) validate() error {
+	var missing []string
+	for field, value := range map[string]string{"repo": b.Repo, "chart": b.Chart, "version": b.Version} {
+		if value == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) != 0 {
+		return apierrors.NewBadRequest(fmt.Sprintf("missing required fields: %s", strings.Join(missing, ", ")))
+	}
+	return validateInstallTimeout(b.TimeoutSeconds)
+}
+
+func validateInstallTimeout(timeoutSeconds int) error {
+	maxSeconds := int(plugin.MaxInstallTimeout / time.Second)
+	if timeoutSeconds < 0 || timeoutSeconds > maxSeconds {
+		return apierrors.NewBadRequest(fmt.Sprintf("timeoutSeconds must be between 0 and %d", maxSeconds))
+	}
+	return nil
+}
+
+// installTimeout returns how long to wait for the ConsolePlugins, the default if timeoutSeconds is 0
+func installTimeout(timeoutSeconds int) time.Duration {
+	if timeoutSeconds == 0 {
+		return plugin.DefaultInstallTimeout
+	}
+	return time.Duration(timeoutSeconds) * time.Second
+}
+
+// installNamespace returns the namespace of the release to install, openfuyao-system by default
+func installNamespace(namespace string) string {
+	if namespace == "" {
+		return constant.PluginManagementServiceDefaultNamespace
+	}
+	return namespace
+}
+
+// authorizeInstall checks that the user may create the Secrets storing the Helm release in the namespace, the
+// install itself impersonating the user, before any chart is pulled or loaded
+func (h *Handler) authorizeInstall(request *restful.Request, response *restful.Response, namespace string) bool {
+	return h.authorized(request, response, releaseStorageAccess("create", namespace))
+}
+
+func writeMarketplaceUnavailable(response *restful.Response) {
+	respJson := &httputil.ResponseJson{
+		Code: constant.ServiceUnavailable,
+		Msg:  "Marketplace service is unavailable",
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+}
+
+func writeMarketplaceError(response *restful.Response, msg string, err error) {
+	zlog.Errorf("%s: %v", msg, err)
+	respJson := &httputil.ResponseJson{
+		Code: constant.BadGateway,
+		Msg:  fmt.Sprintf("%s: %v", msg, err),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusBadGateway, respJson)
+}
+
+// listMarketplaceCharts lists the charts of console extension in the marketplace
+func (h *Handler) listMarketplaceCharts(request *restful.Request, response *restful.Response) {
+	if h.marketplace == nil {
+		writeMarketplaceUnavailable(response)
+		return
+	}
+	charts, err := h.marketplace.ListExtensionCharts(request.Request.Context())
+	if err != nil {
+		writeMarketplaceError(response, "Error listing charts from marketplace", err)
+		return
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: charts,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// installConsolePlugin pulls a chart of console extension from the marketplace, installs it and waits for the
+// ConsolePlugins to become ready for a short while. It responds with 201 if they are ready in time, 202 otherwise,
+// the ConsolePlugins being polled or watched for their status then.
+func (h *Handler) installConsolePlugin(request *restful.Request, response *restful.Response) {
+	body := &installBody{}
+	if err := json.NewDecoder(request.Request.Body).Decode(body); err != nil {
+		zlog.Errorf("Error parsing request body: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ClientError,
+			Msg:  fmt.Sprintf("Error parsing request body: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+		return
+	}
+	if err := body.validate(); err != nil {
+		writeManagerError(response, "Invalid install request", err)
+		return
+	}
+	if h.marketplace == nil {
+		writeMarketplaceUnavailable(response)
+		return
+	}
+	if h.releaseOperator == nil {
+		writeReleasesUnavailable(response)
+		return
+	}
+	namespace := installNamespace(body.Namespace)
+	if !h.authorizeInstall(request, response, namespace) {
+		return
+	}
+
+	ctx := request.Request.Context()
+	archive, err := h.marketplace.PullChart(ctx, body.Repo, body.Chart, body.Version)
+	if err != nil {
+		writeMarketplaceError(response, "Error pulling chart from marketplace", err)
+		return
+	}
+	ch, err := loader.LoadArchive(bytes.NewReader(archive))
+	if err != nil {
+		writeMarketplaceError(response, "Error loading chart from marketplace", err)
+		return
+	}
+	h.installChart(request, response, ch, &plugin.InstallOptions{
+		ReleaseName: body.ReleaseName,
+		Namespace:   namespace,
+		Values:      body.Values,
+		Timeout:     installTimeout(body.TimeoutSeconds),
+	})
+}
+
+// installChart installs the loaded chart of console extension as the user, records the installed ConsolePlugins
+// and responds with the result of the install
+func (h *Handler) installChart(request *restful.Request, response *restful.Response, ch *chart.Chart,
+	opts *plugin.InstallOptions) {
+	// the install goes on if the client goes away, but no longer than Helm and the wait could take
+	detached := context.WithoutCancel(request.Request.Context())
+	ctx, cancel := context.WithTimeout(detached, plugin.InstallRunTimeout+opts.Timeout)
+	defer cancel()
+	user, _ := auth.UserFrom(ctx)
+	result, err := h.releaseOperator.Install(ctx, user, ch, opts)
+	if err != nil {
+		writeManagerError(response, "Error installing chart of console extension", err)
+		return
+	}
+
+	for _, name := range result.ConsolePlugins {
+		record := newChangeRecord(request, name, plugin.ChangeInstalled)
+		record.Fields = []string{"release.revision"}
+		record.NewValue = fmt.Sprintf("%s/%s@%d", result.Release.Namespace, result.Release.Name,
+			result.Release.Revision)
+		h.audit.Record(detached, record)
+	}
+	zlog.Infof("Successfully installed chart %s as release %s/%s", ch.Name(), opts.Namespace, opts.ReleaseName)
+
+	respJson := &httputil.ResponseJson{
+		Code: constant.FileCreated,
+		Msg:  "success",
+		Data: result,
+	}
+	status := http.StatusCreated
+	if !result.Ready {
+		respJson.Code, status = constant.Accepted, http.StatusAccepted
+		respJson.Msg = "installed, the ConsolePlugins are not ready yet"
+	}
+	_ = response.WriteHeaderAndEntity(status, respJson)
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/client/marketplace"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+)
+
+// newTestMarketplace starts a stand-in marketplace serving a chart which is not a console extension and a broken
+// chart archive
+func newTestMarketplace(t *testing.T) *httptest.Server {
+	plain := newTestChartArchive(t, &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "plain", Version: "0.1.0"},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/marketplace/v1/charts", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":200,"msg":"success","data":[` +
+			`{"name":"extension","repo":"local","version":"0.1.0","keywords":["` +
+			constant.FuyaoExtensionKeyword + `"]}]}`))
+	})
+	mux.HandleFunc("/rest/marketplace/v1/repos/local/charts/plain/versions/0.1.0/download",
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(plain)
+		})
+	mux.HandleFunc("/rest/marketplace/v1/repos/local/charts/broken/versions/0.1.0/download",
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("not a chart"))
+		})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestChartArchive returns the .tgz archive of the chart
+func newTestChartArchive(t *testing.T, ch *chart.Chart) []byte {
+	archive, err := chartutil.Save(ch, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestHandlerInstallConsolePlugin(t *testing.T) {
+	server := newTestMarketplace(t)
+	handler := newTestHandler()
+	handler.releaseOperator = plugin.NewReleaseOperator(&rest.Config{}, handler.manager)
+	handler.marketplace = marketplace.NewClient(nil)
+	handler.marketplace.Host = server.URL
+	handler.visibility = &plugin.VisibilityFilter{Reviewer: &fakeAccessReviewer{allowed: map[string]bool{
+		"admin: create secrets in namespace " + constant.PluginManagementServiceDefaultNamespace: true,
+	}}}
+	unavailable := newTestHandler()
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/marketplace/charts").To(handler.listMarketplaceCharts))
+	ws.Route(ws.POST("/consoleplugins/install").To(handler.installConsolePlugin))
+	ws.Route(ws.GET("/unavailable/marketplace/charts").To(unavailable.listMarketplaceCharts))
+	ws.Route(ws.POST("/unavailable/install").To(unavailable.installConsolePlugin))
+	c := restful.NewContainer()
+	c.Add(ws)
+	c.Filter(withTestUser)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		user       string
+		wantStatus int
+	}{
+		{"TestListCharts", "GET", "/consoleplugins/marketplace/charts", "", "admin", http.StatusOK},
+		{"TestListUnavailable", "GET", "/unavailable/marketplace/charts", "", "admin", http.StatusServiceUnavailable},
+		{"TestInvalidBody", "POST", "/consoleplugins/install", "{", "admin", http.StatusBadRequest},
+		{"TestMissingFields", "POST", "/consoleplugins/install", `{"chart":"plain"}`, "admin", http.StatusBadRequest},
+		{"TestInvalidTimeout", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0","timeoutSeconds":-1}`, "admin", http.StatusBadRequest},
+		{"TestTimeoutTooLong", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0","timeoutSeconds":31}`, "admin", http.StatusBadRequest},
+		{"TestNotExtension", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0"}`, "admin", http.StatusBadRequest},
+		{"TestBrokenChart", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"broken","version":"0.1.0"}`, "admin", http.StatusBadGateway},
+		{"TestMissingChart", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"missing","version":"0.1.0"}`, "admin", http.StatusBadGateway},
+		{"TestInstallUnavailable", "POST", "/unavailable/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0"}`, "admin", http.StatusServiceUnavailable},
+		{"TestAnonymous", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0"}`, "", http.StatusUnauthorized},
+		{"TestForbiddenNamespace", "POST", "/consoleplugins/install",
+			`{"repo":"local","chart":"plain","version":"0.1.0","namespace":"kube-system"}`, "admin",
+			http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/rest/plugin-management/v1beta1"+tt.path,
+				strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", restful.MIME_JSON)
+			req.Header.Set("X-Test-User", tt.user)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", resp.Code, tt.wantStatus, resp.Body.String())
+			}
+			if tt.name != "TestListCharts" {
+				return
+			}
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var charts []marketplace.Chart
+			if err = parseResponseData(result, &charts); err != nil {
+				t.Fatal(err)
+			}
+			if len(charts) != 1 || charts[0].Name != "extension" {
+				t.Errorf("charts = %+v", charts)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// ConsolePluginManifest is the verified manifest of a ConsolePlugin returned to the front-end
+type ConsolePluginManifest struct {
+	// Digest is the sha256 digest of the manifest content
+	Digest string `json:"digest"`
+
+	// Manifest is the manifest published by the backend of the ConsolePlugin
+	Manifest *plugin.PluginManifest `json:"manifest"`
+}
+
+// getConsolePluginManifest fetches the manifest from the backend of the ConsolePlugin and returns it once validated,
+// if its digest is the one recorded in the status by the reconciler
+func (h *Handler) getConsolePluginManifest(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+
+	manifest, digest, err := h.manifests.Fetch(request.Request.Context(), cp, h.backendURL(cp))
+	if err != nil {
+		zlog.Errorf("Error fetching manifest of ConsolePlugin %s: %v", sanitizeLogString(pluginName), err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.BadGateway,
+			Msg:  fmt.Sprintf("Error fetching manifest of ConsolePlugin %s: %v", pluginName, err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadGateway, respJson)
+		return
+	}
+	// only the manifest verified by the reconciler is served, a manifest published since then is refused until
+	// the next reconcile verifies it
+	if cp.Status.ManifestDigest != digest {
+		zlog.Warnf("Manifest of ConsolePlugin %s does not match the verified digest %q",
+			sanitizeLogString(pluginName), cp.Status.ManifestDigest)
+		respJson := &httputil.ResponseJson{
+			Code: constant.Conflict,
+			Msg:  fmt.Sprintf("Manifest of ConsolePlugin %s is not verified yet, retry later", pluginName),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusConflict, respJson)
+		return
+	}
+
+	response.Header().Set(constant.ETagHeader, strconv.Quote(digest))
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: &ConsolePluginManifest{
+			Digest:   digest,
+			Manifest: manifest,
+		},
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
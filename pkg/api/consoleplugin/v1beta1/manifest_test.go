@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/plugin"
+)
+
+func TestHandlerGetConsolePluginManifest(t *testing.T) {
+	manifest := `{"name": "test-consoleplugin", "version": "1.0.0", "entryScript": "plugin-entry.js"}`
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/valid/" + plugin.ManifestFileName:
+			_, _ = w.Write([]byte(manifest))
+		case "/invalid/" + plugin.ManifestFileName:
+			_, _ = w.Write([]byte(`{"name": "test-consoleplugin"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer backend.Close()
+
+	verifiedDigest := plugin.ManifestDigest([]byte(manifest))
+	tests := []struct {
+		name           string
+		pluginName     string
+		basePath       string
+		verifiedDigest string
+		wantCode       int
+	}{
+		{"TestValidManifest", "test-consoleplugin", "/valid", verifiedDigest, http.StatusOK},
+		{"TestUnverifiedManifest", "test-consoleplugin", "/valid", "", http.StatusConflict},
+		{"TestChangedManifest", "test-consoleplugin", "/valid", "sha256:old", http.StatusConflict},
+		{"TestInvalidManifest", "test-consoleplugin", "/invalid", verifiedDigest, http.StatusBadGateway},
+		{"TestMissingManifest", "test-consoleplugin", "/missing", verifiedDigest, http.StatusBadGateway},
+		{"TestPluginNotFound", "not-installed", "/valid", verifiedDigest, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := testConsolePluginUnstructured.DeepCopy()
+			if err := unstructured.SetNestedField(obj.Object, tt.verifiedDigest, "status", "manifestDigest"); err != nil {
+				t.Fatal(err)
+			}
+			manager := &plugin.ConsolePluginManager{Client: newFakeDynamicClient(obj)}
+			handler := newHandler(&rest.Config{}, manager, 20<<20)
+			handler.backendURL = func(cp *plugin.ConsolePlugin) string {
+				return backend.URL + tt.basePath
+			}
+			ws := &restful.WebService{}
+			ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+			ws.Route(ws.GET("/consoleplugins/{pluginName}/manifest").To(handler.getConsolePluginManifest))
+			c := restful.NewContainer()
+			c.Add(ws)
+
+			req := httptest.NewRequest("GET",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins/"+tt.pluginName+"/manifest", nil)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tt.wantCode, resp.Body.String())
+			}
+			if resp.Code != http.StatusOK {
+				return
+			}
+
+			respJson, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got ConsolePluginManifest
+			if err = parseResponseData(respJson, &got); err != nil {
+				t.Fatal(err)
+			}
+			wantDigest := plugin.ManifestDigest([]byte(manifest))
+			if got.Digest != wantDigest || got.Manifest.EntryScript != "plugin-entry.js" {
+				t.Errorf("manifest = %+v, want digest %s", got, wantDigest)
+			}
+			if etag := resp.Header().Get("ETag"); etag != strconv.Quote(wantDigest) {
+				t.Errorf("ETag = %s, want %q", etag, wantDigest)
+			}
+		})
+	}
+}
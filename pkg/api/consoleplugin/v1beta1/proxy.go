@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	nethttputil "net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// hopByHopHeaders are meaningful only for a single transport-level connection and must not be forwarded
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// credentialHeaders carry the credentials of the console user, which are never sent to plugin backends
+var credentialHeaders = []string{
+	"Authorization",
+	"Cookie",
+}
+
+// proxyConsolePlugin reverse-proxies the request to the backend service of an enabled and compatible ConsolePlugin.
+// Caching headers like Cache-Control, ETag and Last-Modified are passed through in both directions.
+func (h *Handler) proxyConsolePlugin(request *restful.Request, response *restful.Response) {
+	pluginName := request.PathParameter(constant.PluginName)
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+	if !cp.Spec.Enabled {
+		zlog.Warnf("Refused to proxy to disabled ConsolePlugin %s", sanitizeLogString(pluginName))
+		respJson := &httputil.ResponseJson{
+			Code: constant.Forbidden,
+			Msg:  fmt.Sprintf("ConsolePlugin %s is disabled", pluginName),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusForbidden, respJson)
+		return
+	}
+	if err := h.manager.CheckCompatibility(cp); err != nil {
+		zlog.Warnf("Refused to proxy to incompatible ConsolePlugin %s: %v", sanitizeLogString(pluginName), err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.Forbidden,
+			Msg:  fmt.Sprintf("ConsolePlugin %s is incompatible: %v", pluginName, err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusForbidden, respJson)
+		return
+	}
+
+	target, err := url.Parse(h.backendURL(cp))
+	if err != nil || target.Host == "" {
+		zlog.Errorf("Error resolving backend of ConsolePlugin %s: %v", sanitizeLogString(pluginName), err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.BadGateway,
+			Msg:  fmt.Sprintf("ConsolePlugin %s has no resolvable backend", pluginName),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusBadGateway, respJson)
+		return
+	}
+
+	proxy := &nethttputil.ReverseProxy{
+		Rewrite: func(r *nethttputil.ProxyRequest) {
+			r.Out.URL.Scheme = target.Scheme
+			r.Out.URL.Host = target.Host
+			r.Out.URL.Path = joinProxyPath(target.Path, request.PathParameter(constant.ProxyPath))
+			r.Out.URL.RawPath = ""
+			r.Out.URL.RawQuery = r.In.URL.RawQuery
+			r.Out.Host = target.Host
+			r.SetXForwarded()
+			removeHeaders(r.Out.Header, hopByHopHeaders)
+			removeHeaders(r.Out.Header, credentialHeaders)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			removeHeaders(resp.Header, hopByHopHeaders)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			zlog.Errorf("Error proxying to ConsolePlugin %s: %v", sanitizeLogString(pluginName), err)
+			w.Header().Set("Content-Type", restful.MIME_JSON)
+			w.WriteHeader(http.StatusBadGateway)
+			_ = json.NewEncoder(w).Encode(&httputil.ResponseJson{
+				Code: constant.BadGateway,
+				Msg:  fmt.Sprintf("Error proxying to ConsolePlugin %s: %v", pluginName, err),
+			})
+		},
+	}
+	proxy.ServeHTTP(response, request.Request)
+}
+
+// joinProxyPath joins the requested path under the base path of the backend. The requested path is
+// cleaned so that it could not escape the base path, while a trailing slash is kept.
+func joinProxyPath(basePath, requestPath string) string {
+	joined := path.Join("/", basePath, path.Clean("/"+requestPath))
+	if strings.HasSuffix(requestPath, "/") && joined != "/" {
+		joined += "/"
+	}
+	return joined
+}
+
+// removeHeaders removes the given headers as well as the headers listed in the Connection header
+func removeHeaders(header http.Header, names []string) {
+	for _, connectionHeader := range header.Values("Connection") {
+		for _, name := range strings.Split(connectionHeader, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range names {
+		header.Del(name)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/plugin"
+)
+
+// initTestProxyContainer returns a container proxying to the backend, and the status seen by the filters like the
+// access logs
+func initTestProxyContainer(backendURL string) (*restful.Container, *int) {
+	handler := newHandler(&rest.Config{}, newTestPluginManager(), 20<<20)
+	handler.backendURL = func(cp *plugin.ConsolePlugin) string {
+		return backendURL
+	}
+
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/proxy/{path:*}").
+		Produces(restful.MIME_JSON, "*/*").
+		To(handler.proxyConsolePlugin))
+	c := restful.NewContainer()
+	c.Add(ws)
+	filteredStatus := new(int)
+	c.Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		chain.ProcessFilter(req, resp)
+		*filteredStatus = resp.StatusCode()
+	})
+	return c, filteredStatus
+}
+
+func TestHandlerProxyConsolePlugin(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend-Path", r.URL.RequestURI())
+		w.Header().Set("X-Backend-Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("Connection", "X-Private")
+		w.Header().Set("X-Private", "secret")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("console.log('plugin')"))
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name         string
+		backendURL   string
+		url          string
+		ifNoneMatch  string
+		wantCode     int
+		wantPath     string
+		wantBody     string
+		wantCacheHdr bool
+	}{
+		{
+			"TestProxyAsset",
+			backend.URL + "/ui",
+			"/consoleplugins/test-consoleplugin/proxy/static/main.js?v=1",
+			"",
+			http.StatusOK,
+			"/ui/static/main.js?v=1",
+			"console.log('plugin')",
+			true,
+		},
+		{
+			"TestProxyNotModified",
+			backend.URL + "/",
+			"/consoleplugins/test-consoleplugin/proxy/main.js",
+			`"v1"`,
+			http.StatusNotModified,
+			"/main.js",
+			"",
+			true,
+		},
+		{
+			"TestProxyPathCannotEscapeBasePath",
+			backend.URL + "/ui",
+			"/consoleplugins/test-consoleplugin/proxy/..%2F..%2Fsecret",
+			"",
+			http.StatusOK,
+			"/ui/secret",
+			"console.log('plugin')",
+			true,
+		},
+		{
+			"TestProxyDisabledPlugin",
+			backend.URL + "/ui",
+			"/consoleplugins/dummy-consoleplugin/proxy/main.js",
+			"",
+			http.StatusForbidden,
+			"",
+			"",
+			false,
+		},
+		{
+			"TestProxyNotFound",
+			backend.URL + "/ui",
+			"/consoleplugins/not-installed/proxy/main.js",
+			"",
+			http.StatusNotFound,
+			"",
+			"",
+			false,
+		},
+		{
+			"TestProxyUnreachableBackend",
+			"http://127.0.0.1:1/ui",
+			"/consoleplugins/test-consoleplugin/proxy/main.js",
+			"",
+			http.StatusBadGateway,
+			"",
+			"",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, filteredStatus := initTestProxyContainer(tt.backendURL)
+			// the context of a served request is canceled when the client goes away, the proxy falls back to the
+			// close notifications otherwise
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, "GET",
+				"http://example.com/rest/plugin-management/v1beta1"+tt.url, nil)
+			req.Header.Set("Accept", "*/*")
+			req.Header.Set("Authorization", "Bearer console-token")
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tt.wantCode, resp.Body.String())
+			}
+			if *filteredStatus != tt.wantCode {
+				t.Errorf("status seen by the filters = %d, want %d", *filteredStatus, tt.wantCode)
+			}
+			if got := resp.Header().Get("X-Backend-Path"); got != tt.wantPath {
+				t.Errorf("backend path = %q, want %q", got, tt.wantPath)
+			}
+			if tt.wantBody != "" && resp.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", resp.Body.String(), tt.wantBody)
+			}
+			if !tt.wantCacheHdr {
+				return
+			}
+			if resp.Header().Get("Cache-Control") != "max-age=3600" || resp.Header().Get("ETag") != `"v1"` {
+				t.Errorf("caching headers not preserved: %v", resp.Header())
+			}
+			for _, name := range []string{"Keep-Alive", "Connection", "X-Private", "X-Backend-Authorization"} {
+				if resp.Header().Get(name) != "" {
+					t.Errorf("header %s should not be forwarded: %v", name, resp.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestJoinProxyPath(t *testing.T) {
+	tests := []struct {
+		basePath    string
+		requestPath string
+		want        string
+	}{
+		{"/", "", "/"},
+		{"/", "index.html", "/index.html"},
+		{"/ui", "static/", "/ui/static/"},
+		{"/ui", "../../etc/passwd", "/ui/etc/passwd"},
+		{"", "main.js", "/main.js"},
+	}
+	for _, tt := range tests {
+		if got := joinProxyPath(tt.basePath, tt.requestPath); got != tt.want {
+			t.Errorf("joinProxyPath(%q, %q) = %q, want %q", tt.basePath, tt.requestPath, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Connection", "X-Private, keep-alive")
+	header.Set("X-Private", "secret")
+	header.Set("Upgrade", "websocket")
+	header.Set("Cache-Control", "no-cache")
+	removeHeaders(header, hopByHopHeaders)
+	if len(header) != 1 || header.Get("Cache-Control") != "no-cache" {
+		t.Errorf("removeHeaders() = %v, want only Cache-Control", header)
+	}
+}
@@ -14,29 +14,110 @@
 package v1beta1
 
 import (
+	"net/http"
+
 	"github.com/emicklei/go-restful/v3"
 	"k8s.io/client-go/rest"
 
 	"plugin-management-service/pkg/constant"
-	"plugin-management-service/pkg/zlog"
+	"plugin-management-service/pkg/plugin"
 )
 
 // BindPluginRoute define the webservice, route of release related function
-func BindPluginRoute(webService *restful.WebService, kubeConfig *rest.Config) {
-	handler, err := newHandler(kubeConfig)
-	if err != nil {
-		zlog.Fatalf("consoleplugin handler init failed, err: %v", err)
-	}
+func BindPluginRoute(webService *restful.WebService, kubeConfig *rest.Config, manager *plugin.ConsolePluginManager,
+	maxChartUploadBytes int64) {
+	handler := newHandler(kubeConfig, manager, maxChartUploadBytes)
 
 	webService.Route(webService.GET("/consoleplugins/").
 		Doc("List ConsolePlugins").
+		Param(webService.QueryParameter(constant.Watch, "stream ConsolePlugin events if true").DataType("boolean")).
+		Param(webService.QueryParameter(constant.ResourceVersion, "resourceVersion to resume watching from")).
+		Param(webService.QueryParameter(constant.Entrypoint, "only list ConsolePlugins with the entrypoint, Nav or Side")).
+		Param(webService.QueryParameter(constant.Enabled, "only list enabled or disabled ConsolePlugins").
+			DataType("boolean")).
+		Param(webService.QueryParameter(constant.Release, "only list ConsolePlugins installed by the Helm release")).
+		Param(webService.QueryParameter(constant.LabelSelector, "only list ConsolePlugins matching the label selector")).
+		Param(webService.QueryParameter(constant.Search, "only list ConsolePlugins whose name or displayName contains it")).
+		Param(webService.QueryParameter(constant.SortBy, "sort by name, order or displayName, default to be name")).
+		Param(webService.QueryParameter(constant.Limit, "maximum number of ConsolePlugins returned").DataType("integer")).
+		Param(webService.QueryParameter(constant.Continue, "token from the X-Continue header of the previous page")).
+		Param(webService.QueryParameter(constant.Lang, "BCP-47 locale of the display names, overrides Accept-Language")).
+		Produces(restful.MIME_JSON, mimeEventStream).
 		To(handler.listConsolePlugins))
 
+	webService.Route(webService.GET("/consoleplugins/events").
+		Doc("Stream ConsolePlugin events as Server-Sent Events").
+		Param(webService.QueryParameter(constant.ResourceVersion, "resourceVersion to resume watching from")).
+		Produces(mimeEventStream, restful.MIME_JSON).
+		To(handler.watchConsolePlugins))
+
+	webService.Route(webService.PUT("/consoleplugins/order").
+		Doc("Reorder ConsolePlugins in the menu atomically").
+		Reads(reorderBody{}).
+		Filter(handler.authorize("update")).
+		To(handler.reorderConsolePlugins))
+
+	webService.Route(webService.POST("/consoleplugins/enablement").
+		Doc("Set the enablement of ConsolePlugins in bulk, by list or by label selector").
+		Reads(bulkEnablementBody{}).
+		Filter(handler.authorize("patch")).
+		To(handler.setEnablementBulk))
+
+	webService.Route(webService.GET("/consoleplugins/marketplace/charts").
+		Doc("List the charts of console extension in the marketplace").
+		To(handler.listMarketplaceCharts))
+
+	webService.Route(webService.POST("/consoleplugins/install").
+		Doc("Install ConsolePlugins from a chart in the marketplace and wait for them to become ready").
+		Reads(installBody{}).
+		Filter(handler.authorize("create")).
+		To(handler.installConsolePlugin))
+
+	webService.Route(webService.POST("/consoleplugins/upload").
+		Doc("Install ConsolePlugins from an uploaded chart archive and wait for them to become ready").
+		Consumes(mimeMultipartForm).
+		Param(webService.FormParameter(chartFormField, "chart archive of console extension, .tgz").
+			DataType("file").Required(true)).
+		Param(webService.FormParameter(releaseNameFormField, "release name, default to the chart name")).
+		Param(webService.FormParameter(namespaceFormField, "release namespace, default to openfuyao-system")).
+		Param(webService.FormParameter(valuesFormField, "values overriding the chart defaults, YAML or JSON")).
+		Param(webService.FormParameter(timeoutSecondsFormField, "seconds to wait for the ConsolePlugins to be ready").
+			DataType("integer")).
+		Filter(handler.authorize("create")).
+		To(handler.uploadConsolePlugin))
+
 	webService.Route(webService.GET("/consoleplugins/{pluginName}").
 		Doc("Get ConsolePlugins from name").
 		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Param(webService.QueryParameter(constant.Lang, "BCP-47 locale of the display names, overrides Accept-Language")).
 		To(handler.getConsolePlugin))
 
+	webService.Route(webService.POST("/consoleplugins/").
+		Doc("Create ConsolePlugin").
+		Reads(plugin.ConsolePluginSpec{}).
+		Filter(handler.authorize("create")).
+		To(handler.createConsolePlugin))
+
+	webService.Route(webService.PUT("/consoleplugins/{pluginName}").
+		Doc("Update ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Reads(plugin.ConsolePluginSpec{}).
+		Filter(handler.authorize("update")).
+		To(handler.updateConsolePlugin))
+
+	webService.Route(webService.PATCH("/consoleplugins/{pluginName}").
+		Doc("Patch ConsolePlugin spec with JSON merge patch").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Consumes("application/merge-patch+json", restful.MIME_JSON).
+		Filter(handler.authorize("patch")).
+		To(handler.patchConsolePlugin))
+
+	webService.Route(webService.DELETE("/consoleplugins/{pluginName}").
+		Doc("Delete ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Filter(handler.authorize("delete")).
+		To(handler.deleteConsolePlugin))
+
 	webService.Route(webService.GET("/consoleplugins/{pluginName}/enabled").
 		Doc("Check if the ConsolePlugin is enabled").
 		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
@@ -45,5 +126,59 @@ func BindPluginRoute(webService *restful.WebService, kubeConfig *rest.Config) {
 	webService.Route(webService.POST("/consoleplugins/{pluginName}/enabled").
 		Doc("Set ConsolePlugin Enablement").
 		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Param(webService.QueryParameter(constant.Cascade,
+			"enable the dependencies or disable the dependents as well if true").DataType("boolean")).
+		Param(webService.HeaderParameter(constant.IfMatchHeader,
+			"resourceVersion the ConsolePlugin must still have, same as resourceVersion in the body")).
+		Filter(handler.authorize("patch")).
 		To(handler.setEnablement))
+
+	webService.Route(webService.GET("/consoleplugins/{pluginName}/history").
+		Doc("Get the recent changes of the ConsolePlugin, the latest first").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Param(webService.QueryParameter(constant.Limit, "maximum number of changes returned").DataType("integer")).
+		To(handler.getConsolePluginHistory))
+
+	webService.Route(webService.GET("/consoleplugins/{pluginName}/release").
+		Doc("Get the Helm release which installed the ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		To(handler.getConsolePluginRelease))
+
+	webService.Route(webService.DELETE("/consoleplugins/{pluginName}/release").
+		Doc("Uninstall the Helm release which installed the ConsolePlugin, deleting the ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Param(webService.QueryParameter(constant.DryRun, "simulate the uninstall if true").DataType("boolean")).
+		Filter(handler.authorize("delete")).
+		To(handler.uninstallConsolePluginRelease))
+
+	webService.Route(webService.POST("/consoleplugins/{pluginName}/release/rollback").
+		Doc("Roll back the Helm release which installed the ConsolePlugin, restoring the ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		Param(webService.QueryParameter(constant.Revision, "revision to roll back to, the previous one if 0 or empty").
+			DataType("integer")).
+		Param(webService.QueryParameter(constant.DryRun, "simulate the rollback if true").DataType("boolean")).
+		Filter(handler.authorize("update")).
+		To(handler.rollbackConsolePluginRelease))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		webService.Route(webService.Method(method).Path("/consoleplugins/{pluginName}/icon").
+			Doc("Get the validated icon of the ConsolePlugin, SVG icons are sanitized").
+			Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+			Produces(restful.MIME_JSON, "*/*").
+			To(handler.getConsolePluginIcon))
+	}
+
+	webService.Route(webService.GET("/consoleplugins/{pluginName}/manifest").
+		Doc("Get the verified plugin manifest published by the backend of the ConsolePlugin").
+		Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+		To(handler.getConsolePluginManifest))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		webService.Route(webService.Method(method).Path("/consoleplugins/{pluginName}/proxy/{path:*}").
+			Doc("Proxy to the backend serving the UI resources of an enabled ConsolePlugin").
+			Param(webService.PathParameter(constant.PluginName, "console consoleplugin name").Required(true)).
+			Param(webService.PathParameter(constant.ProxyPath, "path under the base path of the backend")).
+			Produces(restful.MIME_JSON, "*/*").
+			To(handler.proxyConsolePlugin))
+	}
 }
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// writeReleaseError responds with 404 if the ConsolePlugin is not installed by a Helm release, like
+// writeManagerError otherwise
+func writeReleaseError(response *restful.Response, msg string, pluginName string, err error) {
+	if errors.Is(err, plugin.ErrNoRelease) {
+		respJson := &httputil.ResponseJson{
+			Code: constant.ResourceNotFound,
+			Msg:  fmt.Sprintf("ConsolePlugin %s is %v", pluginName, err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusNotFound, respJson)
+		return
+	}
+	writeManagerError(response, msg, err)
+}
+
+func writeReleasesUnavailable(response *restful.Response) {
+	respJson := &httputil.ResponseJson{
+		Code: constant.ServiceUnavailable,
+		Msg:  "Helm releases of ConsolePlugins are unavailable",
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+}
+
+// getConsolePluginRelease returns the Helm release which installed the ConsolePlugin
+func (h *Handler) getConsolePluginRelease(request *restful.Request, response *restful.Response) {
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+	if h.releases == nil {
+		writeReleasesUnavailable(response)
+		return
+	}
+
+	releaseInfo, err := h.releases.Resolve(cp)
+	if err != nil {
+		writeReleaseError(response, "Error getting the Helm release of ConsolePlugin", cp.Name, err)
+		return
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: releaseInfo,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// uninstallConsolePluginRelease uninstalls the Helm release which installed the ConsolePlugin
+func (h *Handler) uninstallConsolePluginRelease(request *restful.Request, response *restful.Response) {
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+	if h.releaseOperator == nil {
+		writeReleasesUnavailable(response)
+		return
+	}
+
+	dryRun := request.QueryParameter(constant.DryRun) == "true"
+	user, _ := auth.UserFrom(request.Request.Context())
+	result, err := h.releaseOperator.Uninstall(user, cp, dryRun)
+	if err != nil {
+		writeReleaseError(response, "Error uninstalling the Helm release of ConsolePlugin", cp.Name, err)
+		return
+	}
+
+	if !dryRun {
+		record := newChangeRecord(request, cp.Name, plugin.ChangeUninstalled)
+		if result.Release != nil {
+			record.Fields = []string{"release.revision"}
+			record.OldValue = strconv.Itoa(result.Release.Revision)
+		}
+		h.audit.Record(request.Request.Context(), record)
+		zlog.Infof("Successfully uninstalled the Helm release of ConsolePlugin %s", cp.Name)
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: result,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
+
+// rollbackConsolePluginRelease rolls back the Helm release which installed the ConsolePlugin
+func (h *Handler) rollbackConsolePluginRelease(request *restful.Request, response *restful.Response) {
+	revision := 0
+	if revisionParam := request.QueryParameter(constant.Revision); revisionParam != "" {
+		var err error
+		if revision, err = strconv.Atoi(revisionParam); err != nil || revision < 0 {
+			writeManagerError(response, "Invalid revision",
+				apierrors.NewBadRequest(fmt.Sprintf("invalid revision %q", revisionParam)))
+			return
+		}
+	}
+	cp, ok := h.visibleConsolePlugin(request, response)
+	if !ok {
+		return
+	}
+	if h.releaseOperator == nil {
+		writeReleasesUnavailable(response)
+		return
+	}
+
+	dryRun := request.QueryParameter(constant.DryRun) == "true"
+	user, _ := auth.UserFrom(request.Request.Context())
+	result, err := h.releaseOperator.Rollback(user, cp, revision, dryRun)
+	if err != nil {
+		writeReleaseError(response, "Error rolling back the Helm release of ConsolePlugin", cp.Name, err)
+		return
+	}
+
+	if !dryRun {
+		record := newChangeRecord(request, cp.Name, plugin.ChangeRolledBack)
+		record.Fields = []string{"release.revision"}
+		record.OldValue = strconv.Itoa(result.PreviousRevision)
+		record.NewValue = strconv.Itoa(result.Release.Revision)
+		h.audit.Record(request.Request.Context(), record)
+		zlog.Infof("Successfully rolled back the Helm release of ConsolePlugin %s", cp.Name)
+	}
+	respJson := &httputil.ResponseJson{
+		Code: constant.Success,
+		Msg:  "success",
+		Data: result,
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, respJson)
+}
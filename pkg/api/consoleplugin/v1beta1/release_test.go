@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/plugin"
+)
+
+func TestHandlerGetConsolePluginRelease(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	err := driver.NewSecrets(client.CoreV1().Secrets("test-release-ns")).Create("sh.helm.release.v1.test-release.v3",
+		&release.Release{
+			Name:      "test-release",
+			Namespace: "test-release-ns",
+			Version:   3,
+			Info:      &release.Info{Status: release.StatusDeployed},
+			Chart: &chart.Chart{
+				Metadata: &chart.Metadata{Name: "test-chart", Version: "0.3.0", AppVersion: "v0.3"},
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newTestHandler()
+	handler.releases = plugin.NewReleaseResolver(client)
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/consoleplugins/{pluginName}/release").To(handler.getConsolePluginRelease))
+	c := restful.NewContainer()
+	c.Add(ws)
+
+	tests := []struct {
+		name       string
+		pluginName string
+		wantStatus int
+		want       plugin.ReleaseInfo
+	}{
+		{"TestRelease", "test-consoleplugin", http.StatusOK, plugin.ReleaseInfo{
+			Name: "test-release", Namespace: "test-release-ns", Revision: 3, Chart: "test-chart",
+			ChartVersion: "0.3.0", AppVersion: "v0.3", Status: "deployed",
+		}},
+		{"TestNoRelease", "dummy-consoleplugin", http.StatusNotFound, plugin.ReleaseInfo{}},
+		{"TestPluginNotFound", "not-installed", http.StatusNotFound, plugin.ReleaseInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET",
+				"http://example.com/rest/plugin-management/v1beta1/consoleplugins/"+tt.pluginName+"/release", nil)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", resp.Code, tt.wantStatus, resp.Body.String())
+			}
+			if resp.Code != http.StatusOK {
+				return
+			}
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got plugin.ReleaseInfo
+			if err = parseResponseData(result, &got); err != nil {
+				t.Fatal(err)
+			}
+			got.LastDeployed = tt.want.LastDeployed
+			if got != tt.want {
+				t.Errorf("release = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerConsolePluginReleaseOperations(t *testing.T) {
+	handler := newTestHandler()
+	handler.releaseOperator = plugin.NewReleaseOperator(&rest.Config{}, handler.manager)
+	unavailable := newTestHandler()
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.DELETE("/consoleplugins/{pluginName}/release").To(handler.uninstallConsolePluginRelease))
+	ws.Route(ws.POST("/consoleplugins/{pluginName}/release/rollback").To(handler.rollbackConsolePluginRelease))
+	ws.Route(ws.POST("/unavailable/{pluginName}/release/rollback").To(unavailable.rollbackConsolePluginRelease))
+	c := restful.NewContainer()
+	c.Add(ws)
+	c.Filter(withTestUser)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		wantStatus int
+	}{
+		{"TestUninstallNoRelease", "DELETE", "/consoleplugins/dummy-consoleplugin/release?dryRun=true", "admin",
+			http.StatusNotFound},
+		{"TestUninstallPluginNotFound", "DELETE", "/consoleplugins/not-installed/release", "admin",
+			http.StatusNotFound},
+		{"TestUninstallAnonymous", "DELETE", "/consoleplugins/test-consoleplugin/release?dryRun=true", "",
+			http.StatusUnauthorized},
+		{"TestRollbackNoRelease", "POST", "/consoleplugins/dummy-consoleplugin/release/rollback", "admin",
+			http.StatusNotFound},
+		{"TestRollbackInvalidRevision", "POST", "/consoleplugins/test-consoleplugin/release/rollback?revision=-1",
+			"admin", http.StatusBadRequest},
+		{"TestRollbackAnonymous", "POST", "/consoleplugins/test-consoleplugin/release/rollback?dryRun=true", "",
+			http.StatusUnauthorized},
+		{"TestRollbackUnavailable", "POST", "/unavailable/test-consoleplugin/release/rollback", "admin",
+			http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/rest/plugin-management/v1beta1"+tt.path, nil)
+			req.Header.Set("X-Test-User", tt.user)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", resp.Code, tt.wantStatus, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/utils/util"
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	mimeMultipartForm = "multipart/form-data"
+
+	chartFormField          = "chart"
+	releaseNameFormField    = "releaseName"
+	namespaceFormField      = "namespace"
+	valuesFormField         = "values"
+	timeoutSecondsFormField = "timeoutSeconds"
+
+	// maxMultipartOverhead is the size allowed for the form fields and the multipart boundaries besides the chart
+	maxMultipartOverhead = 1 << 20
+	// maxMultipartMemory is the size of the form kept in memory, the rest is stored in temporary files
+	maxMultipartMemory = 8 << 20
+	uploadBufferSize   = 32 << 10
+)
+
+func writeChartTooLarge(response *restful.Response, maxBytes int64) {
+	respJson := &httputil.ResponseJson{
+		Code: constant.ExceedChartUploadLimit,
+		Msg:  fmt.Sprintf("Chart archive exceeds the upload limit of %d bytes", maxBytes),
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusBadRequest, respJson)
+}
+
+// uploadConsolePlugin installs the chart of console extension uploaded as the chart field of a multipart form,
+// for the clusters which could not reach the marketplace. It responds like installConsolePlugin. The user is
+// authorized to create the release in the namespace before the archive is read.
+func (h *Handler) uploadConsolePlugin(request *restful.Request, response *restful.Response) {
+	if h.releaseOperator == nil {
+		writeReleasesUnavailable(response)
+		return
+	}
+	httpRequest := request.Request
+	httpRequest.Body = http.MaxBytesReader(response.ResponseWriter, httpRequest.Body,
+		h.maxChartUploadBytes+maxMultipartOverhead)
+	if err := httpRequest.ParseMultipartForm(maxMultipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeChartTooLarge(response, h.maxChartUploadBytes)
+			return
+		}
+		writeManagerError(response, "Invalid upload request",
+			apierrors.NewBadRequest(fmt.Sprintf("invalid multipart form: %v", err)))
+		return
+	}
+	defer func() {
+		_ = httpRequest.MultipartForm.RemoveAll()
+	}()
+
+	file, header, err := httpRequest.FormFile(chartFormField)
+	if err != nil {
+		writeManagerError(response, "Invalid upload request",
+			apierrors.NewBadRequest(fmt.Sprintf("missing chart archive in form field %s", chartFormField)))
+		return
+	}
+	defer file.Close()
+	opts, err := uploadInstallOptions(httpRequest.MultipartForm)
+	if err == nil && !strings.HasSuffix(path.Base(header.Filename), ".tgz") {
+		err = apierrors.NewBadRequest(fmt.Sprintf("chart archive %s is not a .tgz file", header.Filename))
+	}
+	if err != nil {
+		writeManagerError(response, "Invalid upload request", err)
+		return
+	}
+	opts.Namespace = installNamespace(opts.Namespace)
+	if !h.authorizeInstall(request, response, opts.Namespace) {
+		return
+	}
+
+	ok, err := util.CheckFileSize(file, uploadBufferSize, h.maxChartUploadBytes)
+	if err == nil && ok {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		writeManagerError(response, "Error reading uploaded chart archive", err)
+		return
+	}
+	if !ok {
+		writeChartTooLarge(response, h.maxChartUploadBytes)
+		return
+	}
+	ch, err := loader.LoadArchive(file)
+	if err != nil {
+		writeManagerError(response, "Invalid upload request",
+			apierrors.NewBadRequest(fmt.Sprintf("invalid chart archive %s: %v", header.Filename, err)))
+		return
+	}
+	zlog.Infof("Installing uploaded chart %s-%s", ch.Name(), ch.Metadata.Version)
+	h.installChart(request, response, ch, opts)
+}
+
+// uploadInstallOptions reads the install options from the fields of the upload form
+func uploadInstallOptions(form *multipart.Form) (*plugin.InstallOptions, error) {
+	field := func(name string) string {
+		if values := form.Value[name]; len(values) != 0 {
+			return values[0]
+		}
+		return ""
+	}
+	opts := &plugin.InstallOptions{
+		ReleaseName: field(releaseNameFormField),
+		Namespace:   field(namespaceFormField),
+		Timeout:     installTimeout(0),
+	}
+	if values := field(valuesFormField); values != "" {
+		if err := yaml.Unmarshal([]byte(values), &opts.Values); err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid values: %v", err))
+		}
+	}
+	if timeout := field(timeoutSecondsFormField); timeout != "" {
+		timeoutSeconds, err := strconv.Atoi(timeout)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid timeoutSeconds %q", timeout))
+		}
+		if err = validateInstallTimeout(timeoutSeconds); err != nil {
+			return nil, err
+		}
+		opts.Timeout = installTimeout(timeoutSeconds)
+	}
+	return opts, nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+)
+
+// newTestUploadForm returns a multipart form uploading the archive as filename, with the fields
+func newTestUploadForm(t *testing.T, filename string, archive []byte, fields map[string]string) (*bytes.Buffer,
+	string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if filename != "" {
+		part, err := writer.CreateFormFile(chartFormField, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = part.Write(archive); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, writer.FormDataContentType()
+}
+
+func TestHandlerUploadConsolePlugin(t *testing.T) {
+	plain := newTestChartArchive(t, &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "plain", Version: "0.1.0"},
+	})
+	handler := newTestHandler()
+	handler.releaseOperator = plugin.NewReleaseOperator(&rest.Config{}, handler.manager)
+	handler.maxChartUploadBytes = int64(len(plain))
+	handler.visibility = &plugin.VisibilityFilter{Reviewer: &fakeAccessReviewer{allowed: map[string]bool{
+		"admin: create secrets in namespace " + constant.PluginManagementServiceDefaultNamespace: true,
+	}}}
+	unavailable := newTestHandler()
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/consoleplugins/upload").Consumes(mimeMultipartForm).To(handler.uploadConsolePlugin))
+	ws.Route(ws.POST("/unavailable/upload").Consumes(mimeMultipartForm).To(unavailable.uploadConsolePlugin))
+	c := restful.NewContainer()
+	c.Add(ws)
+	c.Filter(withTestUser)
+
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		filename   string
+		archive    []byte
+		fields     map[string]string
+		wantStatus int
+		wantCode   int32
+	}{
+		{"TestNotExtension", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", plain, nil,
+			http.StatusBadRequest, constant.ClientError},
+		{"TestMissingChart", "/consoleplugins/upload", "admin", "", nil, nil,
+			http.StatusBadRequest, constant.ClientError},
+		{"TestNotTgz", "/consoleplugins/upload", "admin", "plain.zip", plain, nil,
+			http.StatusBadRequest, constant.ClientError},
+		{"TestInvalidArchive", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", []byte("not a chart"), nil,
+			http.StatusBadRequest, constant.ClientError},
+		{"TestTooLarge", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", append(plain, 0), nil,
+			http.StatusBadRequest, constant.ExceedChartUploadLimit},
+		{"TestInvalidValues", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", plain,
+			map[string]string{valuesFormField: "[invalid"}, http.StatusBadRequest, constant.ClientError},
+		{"TestInvalidTimeout", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", plain,
+			map[string]string{timeoutSecondsFormField: "forever"}, http.StatusBadRequest, constant.ClientError},
+		{"TestUnavailable", "/unavailable/upload", "admin", "plain-0.1.0.tgz", plain, nil,
+			http.StatusServiceUnavailable, constant.ServiceUnavailable},
+		{"TestAnonymous", "/consoleplugins/upload", "", "plain-0.1.0.tgz", plain, nil,
+			http.StatusUnauthorized, constant.Unauthorized},
+		{"TestForbiddenNamespace", "/consoleplugins/upload", "admin", "plain-0.1.0.tgz", []byte("not a chart"),
+			map[string]string{namespaceFormField: "kube-system"}, http.StatusForbidden, constant.Forbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := newTestUploadForm(t, tt.filename, tt.archive, tt.fields)
+			req := httptest.NewRequest("POST", "http://example.com/rest/plugin-management/v1beta1"+tt.path, body)
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("X-Test-User", tt.user)
+			resp := httptest.NewRecorder()
+			c.Dispatch(resp, req)
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", resp.Code, tt.wantStatus, resp.Body.String())
+			}
+			result, err := parseResponseJSON(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d, msg %s", result.Code, tt.wantCode, result.Msg)
+			}
+		})
+	}
+}
+
+func TestHandlerUploadConsolePluginNotMultipart(t *testing.T) {
+	handler := newTestHandler()
+	handler.releaseOperator = plugin.NewReleaseOperator(&rest.Config{}, handler.manager)
+	handler.maxChartUploadBytes = 1 << 20
+	ws := &restful.WebService{}
+	ws.Path("/rest/plugin-management/v1beta1").Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/consoleplugins/upload").To(handler.uploadConsolePlugin))
+	c := restful.NewContainer()
+	c.Add(ws)
+
+	req := httptest.NewRequest("POST", "http://example.com/rest/plugin-management/v1beta1/consoleplugins/upload",
+		strings.NewReader("{}"))
+	req.Header.Set("Content-Type", restful.MIME_JSON)
+	resp := httptest.NewRecorder()
+	c.Dispatch(resp, req)
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d, body %s", resp.Code, http.StatusBadRequest, resp.Body.String())
+	}
+}
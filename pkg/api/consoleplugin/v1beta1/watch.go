@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"golang.org/x/text/language"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"plugin-management-service/pkg/auth"
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/plugin"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	mimeEventStream        = "text/event-stream"
+	watchHeartbeatInterval = 15 * time.Second
+	lastEventIDHeader      = "Last-Event-ID"
+)
+
+// ConsolePluginEvent is a change of a ConsolePlugin streamed to the front-end
+type ConsolePluginEvent struct {
+	Type            watch.EventType      `json:"type"`
+	ResourceVersion string               `json:"resourceVersion"`
+	Object          ConsolePluginTrimmed `json:"object"`
+}
+
+// watchConsolePlugins streams ADDED/MODIFIED/DELETED events of ConsolePlugins as Server-Sent Events.
+// The stream resumes from the resourceVersion query parameter or the Last-Event-ID header if given.
+func (h *Handler) watchConsolePlugins(request *restful.Request, response *restful.Response) {
+	resourceVersion := request.QueryParameter(constant.ResourceVersion)
+	if resourceVersion == "" {
+		resourceVersion = request.HeaderParameter(lastEventIDHeader)
+	}
+
+	flusher, ok := response.ResponseWriter.(http.Flusher)
+	if !ok {
+		zlog.Errorf("Streaming is not supported by the response writer")
+		respJson := &httputil.ResponseJson{
+			Code: constant.ServerError,
+			Msg:  "streaming is not supported",
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, respJson)
+		return
+	}
+
+	ctx := request.Request.Context()
+	watcher, err := h.manager.WatchConsolePlugins(ctx, resourceVersion)
+	if err != nil {
+		zlog.Errorf("Error watching ConsolePlugins: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ServerError,
+			Msg:  fmt.Sprintf("Error watching ConsolePlugins: %v", err),
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, respJson)
+		return
+	}
+	defer watcher.Stop()
+	langs := preferredLanguages(request)
+	user, _ := auth.UserFrom(ctx)
+	shown := h.visibleNames(ctx, user)
+
+	header := response.Header()
+	header.Set("Content-Type", mimeEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(response, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, open := <-watcher.ResultChan():
+			if !open {
+				return
+			}
+			if err = h.writeConsolePluginEvent(ctx, response, event, user, langs, shown); err != nil {
+				zlog.Warnf("Stop streaming ConsolePlugin events: %v", err)
+				return
+			}
+			flusher.Flush()
+			if event.Type == watch.Error {
+				return
+			}
+		}
+	}
+}
+
+// visibleNames returns the names of the ConsolePlugins visible to the user when the stream starts, those the client
+// could have listed before watching
+func (h *Handler) visibleNames(ctx context.Context, user *authenticationv1.UserInfo) map[string]bool {
+	names := make(map[string]bool)
+	consolePlugins, err := h.manager.ListConsolePlugins()
+	if err != nil {
+		zlog.Warnf("Error listing ConsolePlugins, the hidden ones are not removed from the stream: %v", err)
+		return names
+	}
+	for _, cp := range h.visibility.Filter(ctx, user, consolePlugins) {
+		names[cp.Name] = true
+	}
+	return names
+}
+
+// writeConsolePluginEvent writes a watch event in Server-Sent Events format. The ConsolePlugins shown to the user
+// in the stream are tracked, one becoming hidden is streamed as DELETED.
+func (h *Handler) writeConsolePluginEvent(ctx context.Context, response *restful.Response, event watch.Event,
+	user *authenticationv1.UserInfo, langs []language.Tag, shown map[string]bool) error {
+	if event.Type == watch.Error {
+		status := &metav1.Status{}
+		if s, ok := event.Object.(*metav1.Status); ok {
+			status = s
+		} else if u, ok := event.Object.(runtime.Unstructured); ok {
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), status)
+		}
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.Type, data)
+		return err
+	}
+
+	accessor, err := meta.Accessor(event.Object)
+	if err != nil {
+		return err
+	}
+	if event.Type == watch.Bookmark {
+		// bookmarks only move the resume point of the client forward
+		_, err = fmt.Fprintf(response, "id: %s\n\n", accessor.GetResourceVersion())
+		return err
+	}
+
+	u, ok := event.Object.(runtime.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", event.Object)
+	}
+	var cp plugin.ConsolePlugin
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &cp); err != nil {
+		return err
+	}
+	consolePluginEvent := &ConsolePluginEvent{Type: event.Type, ResourceVersion: accessor.GetResourceVersion()}
+	if visible, ok := h.visibility.FilterOne(ctx, user, &cp); ok {
+		if event.Type == watch.Deleted {
+			delete(shown, cp.Name)
+		} else {
+			shown[cp.Name] = true
+		}
+		consolePluginEvent.Object = h.newConsolePluginTrimmed(visible, langs)
+	} else if shown[cp.Name] {
+		// the client removes the ConsolePlugin, nothing more of it is revealed
+		delete(shown, cp.Name)
+		consolePluginEvent.Type = watch.Deleted
+		consolePluginEvent.Object = ConsolePluginTrimmed{PluginName: cp.Spec.PluginName}
+	} else {
+		// events of the ConsolePlugins never shown to the user are skipped
+		return nil
+	}
+	data, err := json.Marshal(consolePluginEvent)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(response, "id: %s\nevent: %s\ndata: %s\n\n", accessor.GetResourceVersion(),
+		consolePluginEvent.Type, data)
+	return err
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultTokenReviewTTL is how long an authenticated token is cached
+	DefaultTokenReviewTTL = time.Minute
+
+	// DefaultFailedTokenReviewTTL is how long a token which failed the authentication is cached
+	DefaultFailedTokenReviewTTL = 10 * time.Second
+
+	maxCachedTokenReviews = 4096
+)
+
+// ErrInvalidToken is returned when the bearer token is not authenticated by the TokenReview
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// TokenAuthenticator authenticates bearer tokens with TokenReviews, the results are cached by the token hash
+type TokenAuthenticator struct {
+	kubeClient kubernetes.Interface
+
+	// Audiences are the audiences the tokens are expected to be issued for, the API server's if empty
+	Audiences []string
+
+	ttl       time.Duration
+	failedTTL time.Duration
+	results   *cache.LRUExpireCache
+}
+
+type tokenReviewResult struct {
+	user  *authenticationv1.UserInfo
+	error string
+}
+
+// NewTokenAuthenticator returns a new TokenAuthenticator with the default TTLs
+func NewTokenAuthenticator(kubeClient kubernetes.Interface) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		kubeClient: kubeClient,
+		ttl:        DefaultTokenReviewTTL,
+		failedTTL:  DefaultFailedTokenReviewTTL,
+		results:    cache.NewLRUExpireCache(maxCachedTokenReviews),
+	}
+}
+
+// AuthenticateToken returns the user of the token. An error wrapping ErrInvalidToken is returned if the token
+// is not authenticated, other errors mean the TokenReview could not be made.
+func (a *TokenAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticationv1.UserInfo,
+	error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := a.results.Get(key); ok {
+		result := cached.(*tokenReviewResult)
+		if result.user == nil {
+			return nil, invalidTokenError(result.error)
+		}
+		return result.user, nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.Audiences,
+		},
+	}
+	result, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Status.Authenticated {
+		a.results.Add(key, &tokenReviewResult{error: result.Status.Error}, a.failedTTL)
+		return nil, invalidTokenError(result.Status.Error)
+	}
+	user := result.Status.User.DeepCopy()
+	a.results.Add(key, &tokenReviewResult{user: user}, a.ttl)
+	return user, nil
+}
+
+func invalidTokenError(reason string) error {
+	if reason == "" {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+}
+
+// UserFromClientCert returns the user of the verified client certificate like the API server does, the common
+// name being the username and the organizations being the groups. False is returned if no certificate was
+// verified, the server only verifies the certificates signed by its client CA.
+func UserFromClientCert(state *tls.ConnectionState) (*authenticationv1.UserInfo, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := state.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, false
+	}
+	return &authenticationv1.UserInfo{
+		Username: cert.Subject.CommonName,
+		Groups:   append([]string(nil), cert.Subject.Organization...),
+	}, true
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"plugin-management-service/pkg/utils/testutil"
+)
+
+const (
+	testToken    = "valid-token"
+	brokenToken  = "broken-token"
+	testUsername = "admin"
+)
+
+// reviewTestToken only authenticates the test token
+func reviewTestToken(review *authenticationv1.TokenReview) error {
+	switch review.Spec.Token {
+	case brokenToken:
+		return errors.New("authenticator unavailable")
+	case testToken:
+		review.Status.Authenticated = true
+		review.Status.User = authenticationv1.UserInfo{Username: testUsername, Groups: []string{"system:masters"}}
+	default:
+		review.Status.Error = "token expired"
+	}
+	return nil
+}
+
+func TestTokenAuthenticatorAuthenticateToken(t *testing.T) {
+	reviews := 0
+	authenticator := NewTokenAuthenticator(testutil.NewTokenReviewClient(&reviews, reviewTestToken))
+	tests := []struct {
+		name        string
+		token       string
+		wantUser    string
+		wantInvalid bool
+		wantErr     bool
+		wantReviews int
+	}{
+		{"TestValidToken", testToken, testUsername, false, false, 1},
+		{"TestValidTokenCached", testToken, testUsername, false, false, 1},
+		{"TestInvalidToken", "expired-token", "", true, true, 2},
+		{"TestInvalidTokenCached", "expired-token", "", true, true, 2},
+		{"TestReviewError", brokenToken, "", false, true, 3},
+		{"TestReviewErrorNotCached", brokenToken, "", false, true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := authenticator.AuthenticateToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrInvalidToken) != tt.wantInvalid {
+				t.Fatalf("AuthenticateToken() error = %v, wantErr %v, wantInvalid %v", err, tt.wantErr, tt.wantInvalid)
+			}
+			if got := username(user); got != tt.wantUser {
+				t.Errorf("AuthenticateToken() user = %q, want %q", got, tt.wantUser)
+			}
+			if reviews != tt.wantReviews {
+				t.Errorf("TokenReviews = %d, want %d", reviews, tt.wantReviews)
+			}
+		})
+	}
+}
+
+func username(user *authenticationv1.UserInfo) string {
+	if user == nil {
+		return ""
+	}
+	return user.Username
+}
+
+func newTestConnectionState(commonName string, organizations ...string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName, Organization: organizations}}
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+}
+
+func TestUserFromClientCert(t *testing.T) {
+	unverified := newTestConnectionState("admin")
+	unverified.VerifiedChains = nil
+	tests := []struct {
+		name       string
+		state      *tls.ConnectionState
+		wantUser   string
+		wantGroups int
+		wantOk     bool
+	}{
+		{"TestNoTLS", nil, "", 0, false},
+		{"TestUnverifiedCert", unverified, "", 0, false},
+		{"TestNoCommonName", newTestConnectionState(""), "", 0, false},
+		{"TestVerifiedCert", newTestConnectionState("admin", "system:masters", "ops"), "admin", 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, ok := UserFromClientCert(tt.state)
+			if ok != tt.wantOk || username(user) != tt.wantUser || (user != nil && len(user.Groups) != tt.wantGroups) {
+				t.Errorf("UserFromClientCert() = %v, %v, want %q with %d groups, %v", user, ok, tt.wantUser,
+					tt.wantGroups, tt.wantOk)
+			}
+		})
+	}
+}
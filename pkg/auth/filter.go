@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/utils/httputil"
+	"plugin-management-service/pkg/zlog"
+)
+
+// TokenReviewer authenticates bearer tokens
+type TokenReviewer interface {
+	AuthenticateToken(ctx context.Context, token string) (*authenticationv1.UserInfo, error)
+}
+
+// Authenticator is a go-restful filter putting the user of the request on its context
+type Authenticator struct {
+	// Tokens authenticates the bearer tokens in the Authorization header
+	Tokens TokenReviewer
+
+	// ClientCerts authenticates the users presenting a client certificate verified by the server if true
+	ClientCerts bool
+}
+
+// Filter authenticates the request with the bearer token, or the client certificate if enabled and no token is
+// given. Anonymous requests may only read, mutations without user get 401, so does any invalid token.
+func (a *Authenticator) Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	user, err := a.authenticate(req.Request)
+	if errors.Is(err, ErrInvalidToken) {
+		writeUnauthorized(resp, err.Error())
+		return
+	}
+	if err != nil {
+		zlog.Errorf("Error authenticating request: %v", err)
+		respJson := &httputil.ResponseJson{
+			Code: constant.ServiceUnavailable,
+			Msg:  "authentication is unavailable",
+		}
+		_ = resp.WriteHeaderAndEntity(http.StatusServiceUnavailable, respJson)
+		return
+	}
+	if user == nil {
+		if !isReadOnly(req.Request.Method) {
+			writeUnauthorized(resp, "authentication is required")
+			return
+		}
+		chain.ProcessFilter(req, resp)
+		return
+	}
+	req.Request = req.Request.WithContext(WithUser(req.Request.Context(), user))
+	chain.ProcessFilter(req, resp)
+}
+
+// authenticate returns the user of the request, nil if the request is anonymous
+func (a *Authenticator) authenticate(r *http.Request) (*authenticationv1.UserInfo, error) {
+	if token, ok := bearerToken(r); ok {
+		if a.Tokens == nil {
+			return nil, errors.New("no token authenticator")
+		}
+		return a.Tokens.AuthenticateToken(r.Context(), token)
+	}
+	if a.ClientCerts {
+		if user, ok := UserFromClientCert(r.TLS); ok {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func isReadOnly(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func writeUnauthorized(resp *restful.Response, msg string) {
+	resp.Header().Set("WWW-Authenticate", `Bearer realm="plugin-management-service"`)
+	respJson := &httputil.ResponseJson{
+		Code: constant.Unauthorized,
+		Msg:  msg,
+	}
+	_ = resp.WriteHeaderAndEntity(http.StatusUnauthorized, respJson)
+}
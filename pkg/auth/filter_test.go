@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"plugin-management-service/pkg/utils/testutil"
+)
+
+// newTestContainer returns a container whose routes answer with the user on the request context
+func newTestContainer(authenticator *Authenticator) *restful.Container {
+	ws := new(restful.WebService)
+	ws.Path("/test").Produces(restful.MIME_JSON, restful.MIME_OCTET).Filter(authenticator.Filter)
+	whoami := func(req *restful.Request, resp *restful.Response) {
+		user, _ := UserFrom(req.Request.Context())
+		_, _ = resp.Write([]byte(username(user)))
+	}
+	ws.Route(ws.GET("").To(whoami))
+	ws.Route(ws.POST("").To(whoami))
+	container := restful.NewContainer()
+	container.Add(ws)
+	return container
+}
+
+func TestAuthenticatorFilter(t *testing.T) {
+	reviews := 0
+	container := newTestContainer(&Authenticator{
+		Tokens:      NewTokenAuthenticator(testutil.NewTokenReviewClient(&reviews, reviewTestToken)),
+		ClientCerts: true,
+	})
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		tls           *tlsState
+		wantStatus    int
+		wantUser      string
+	}{
+		{"TestValidToken", http.MethodPost, "Bearer " + testToken, nil, http.StatusOK, testUsername},
+		{"TestLowercaseScheme", http.MethodGet, "bearer " + testToken, nil, http.StatusOK, testUsername},
+		{"TestInvalidToken", http.MethodGet, "Bearer expired-token", nil, http.StatusUnauthorized, ""},
+		{"TestReviewError", http.MethodGet, "Bearer " + brokenToken, nil, http.StatusServiceUnavailable, ""},
+		{"TestAnonymousRead", http.MethodGet, "", nil, http.StatusOK, ""},
+		{"TestAnonymousMutation", http.MethodPost, "", nil, http.StatusUnauthorized, ""},
+		{"TestBasicAuthIsAnonymous", http.MethodPost, "Basic YWRtaW46YWRtaW4=", nil, http.StatusUnauthorized, ""},
+		{"TestClientCert", http.MethodPost, "", &tlsState{"ops", []string{"ops"}}, http.StatusOK, "ops"},
+		{"TestTokenPreferredOverClientCert", http.MethodPost, "Bearer " + testToken,
+			&tlsState{"ops", nil}, http.StatusOK, testUsername},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.tls != nil {
+				req.TLS = newTestConnectionState(tt.tls.commonName, tt.tls.organizations...)
+			}
+			rec := httptest.NewRecorder()
+			container.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.wantUser {
+				t.Errorf("user = %q, want %q", rec.Body.String(), tt.wantUser)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func TestAuthenticatorFilterClientCertsDisabled(t *testing.T) {
+	container := newTestContainer(&Authenticator{})
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.TLS = newTestConnectionState("ops")
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+type tlsState struct {
+	commonName    string
+	organizations []string
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+// Package auth holds the identity of the console user calling the service
+package auth
+
+import (
+	"context"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// Anonymous is the username of the requests without user, as the API server names it
+const Anonymous = "system:anonymous"
+
+type userKey struct{}
+
+// WithUser returns a copy of the context carrying the user
+func WithUser(ctx context.Context, user *authenticationv1.UserInfo) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the user carried by the context, false if the user is unknown
+func UserFrom(ctx context.Context) (*authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(*authenticationv1.UserInfo)
+	return user, ok && user != nil
+}
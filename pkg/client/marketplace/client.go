@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+// Package marketplace is the client of the marketplace service publishing the charts of console plugins
+package marketplace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/utils/util"
+)
+
+const (
+	// HostConfigKey is the key of the marketplace service host in the config of the service
+	HostConfigKey = "marketplace-service-host"
+
+	// ChartsPathConfigKey is the key of the path listing the charts of the marketplace in the config of the service
+	ChartsPathConfigKey = "marketplace-charts-path"
+
+	// ChartDownloadPathConfigKey is the key of the path downloading a chart archive in the config of the service
+	ChartDownloadPathConfigKey = "marketplace-chart-download-path"
+
+	// DefaultChartsPath lists the charts if the path is not configured, queried with the keyword parameter
+	DefaultChartsPath = "/rest/marketplace/v1/charts"
+
+	// DefaultChartDownloadPath downloads a chart archive if the path is not configured, {repo}, {chart} and
+	// {version} being replaced with the escaped values
+	DefaultChartDownloadPath = "/rest/marketplace/v1/repos/{repo}/charts/{chart}/versions/{version}/download"
+
+	// MaxChartBytes is the maximum size of a chart archive pulled from the marketplace
+	MaxChartBytes = 20 << 20
+
+	configFileKey = "config.yaml"
+
+	maxListBytes = 4 << 20
+)
+
+// ErrNoHost is returned when the marketplace service host is not configured
+var ErrNoHost = errors.New("marketplace service host is not configured")
+
+// Chart is a chart published by the marketplace
+type Chart struct {
+	Name        string   `json:"name"`
+	Repo        string   `json:"repo"`
+	Version     string   `json:"version"`
+	AppVersion  string   `json:"appVersion,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+}
+
+// listResponse is the envelope of the marketplace responses, the same as the one of this service
+type listResponse struct {
+	Code int32   `json:"code"`
+	Msg  string  `json:"msg"`
+	Data []Chart `json:"data"`
+}
+
+// Client reads the charts of console plugins from the marketplace service. The host and the paths of the service
+// are read from the config of this service on every call, so that changes of the ConfigMap apply without restart.
+type Client struct {
+	kubeClient kubernetes.Interface
+	httpClient *http.Client
+
+	// Host overrides the host in the config if not empty
+	Host string
+
+	// ChartsPath and ChartDownloadPath override the paths in the config if not empty
+	ChartsPath        string
+	ChartDownloadPath string
+}
+
+// endpoint is where the marketplace service serves the charts
+type endpoint struct {
+	host              string
+	chartsPath        string
+	chartDownloadPath string
+}
+
+// NewClient returns a new Client reading the marketplace host from the config of the service
+func NewClient(kubeClient kubernetes.Interface) *Client {
+	return &Client{
+		kubeClient: kubeClient,
+		httpClient: &http.Client{
+			Timeout: constant.DefaultHttpRequestSeconds * time.Second,
+		},
+	}
+}
+
+// endpoint returns the marketplace service host without trailing slash and the paths of the charts, the default
+// paths if they are neither overridden nor configured
+func (c *Client) endpoint(ctx context.Context) (*endpoint, error) {
+	// the config is not read if the host is overridden
+	config := map[string]string{}
+	if c.Host == "" {
+		var err error
+		if config, err = c.readConfig(ctx); err != nil {
+			return nil, err
+		}
+	}
+	e := &endpoint{
+		host:       strings.TrimSuffix(firstNonEmpty(c.Host, config[HostConfigKey]), "/"),
+		chartsPath: firstNonEmpty(c.ChartsPath, config[ChartsPathConfigKey], DefaultChartsPath),
+		chartDownloadPath: firstNonEmpty(c.ChartDownloadPath, config[ChartDownloadPathConfigKey],
+			DefaultChartDownloadPath),
+	}
+	if e.host == "" {
+		return nil, ErrNoHost
+	}
+	return e, nil
+}
+
+// readConfig returns the config of the service, empty if there is no kubernetes client
+func (c *Client) readConfig(ctx context.Context) (map[string]string, error) {
+	config := map[string]string{}
+	if c.kubeClient == nil {
+		return config, nil
+	}
+	cm, err := c.kubeClient.CoreV1().ConfigMaps(constant.PluginManagementServiceDefaultNamespace).
+		Get(ctx, constant.PluginManagementServiceConfigmap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading config %s: %w", constant.PluginManagementServiceConfigmap, err)
+	}
+	if err = yaml.Unmarshal([]byte(cm.Data[configFileKey]), &config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", constant.PluginManagementServiceConfigmap, err)
+	}
+	return config, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ListExtensionCharts returns the charts of the marketplace tagged with the openfuyao-extension keyword
+func (c *Client) ListExtensionCharts(ctx context.Context) ([]Chart, error) {
+	e, err := c.endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"keyword": []string{constant.FuyaoExtensionKeyword}}
+	body, err := c.get(ctx, e.host+e.chartsPath+"?"+query.Encode(), maxListBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp listResponse
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid chart list from marketplace: %w", err)
+	}
+	// the keyword filter of the marketplace is a search, not every chart found is tagged
+	charts := make([]Chart, 0, len(resp.Data))
+	for _, chart := range resp.Data {
+		if util.Contains(chart.Keywords, constant.FuyaoExtensionKeyword) {
+			charts = append(charts, chart)
+		}
+	}
+	return charts, nil
+}
+
+// PullChart downloads the archive of the chart version from the marketplace
+func (c *Client) PullChart(ctx context.Context, repo, name, version string) ([]byte, error) {
+	e, err := c.endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.NewReplacer("{repo}", url.PathEscape(repo), "{chart}", url.PathEscape(name),
+		"{version}", url.PathEscape(version)).Replace(e.chartDownloadPath)
+	return c.get(ctx, e.host+path, MaxChartBytes)
+}
+
+func (c *Client) get(ctx context.Context, link string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP GET %s returned status code %d", link, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("HTTP GET %s returned more than %d bytes", link, maxBytes)
+	}
+	return body, nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"plugin-management-service/pkg/constant"
+)
+
+// newTestMarketplace starts a stand-in marketplace serving one extension chart and one chart merely matching the
+// keyword search, at the default paths
+func newTestMarketplace(t *testing.T) *httptest.Server {
+	return newTestMarketplaceAt(t, DefaultChartsPath,
+		"/rest/marketplace/v1/repos/local/charts/extension/versions/0.1.0/download")
+}
+
+func newTestMarketplaceAt(t *testing.T, chartsPath, downloadPath string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(chartsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("keyword") != constant.FuyaoExtensionKeyword {
+			t.Errorf("keyword = %q", r.URL.Query().Get("keyword"))
+		}
+		_, _ = fmt.Fprintf(w, `{"code":200,"msg":"success","data":[
+			{"name":"extension","repo":"local","version":"0.1.0","keywords":[%q]},
+			{"name":"other","repo":"local","version":"1.0.0","description":"mentions openfuyao-extension"}]}`,
+			constant.FuyaoExtensionKeyword)
+	})
+	mux.HandleFunc(downloadPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("archive"))
+		})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestConfigMap(host string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constant.PluginManagementServiceConfigmap,
+			Namespace: constant.PluginManagementServiceDefaultNamespace,
+		},
+		Data: map[string]string{configFileKey: HostConfigKey + ": " + host + "/\n"},
+	}
+}
+
+func TestClientListExtensionCharts(t *testing.T) {
+	server := newTestMarketplace(t)
+	client := NewClient(kubefake.NewSimpleClientset(newTestConfigMap(server.URL)))
+
+	charts, err := client.ListExtensionCharts(context.Background())
+	if err != nil {
+		t.Fatalf("ListExtensionCharts() error = %v", err)
+	}
+	want := []Chart{{Name: "extension", Repo: "local", Version: "0.1.0",
+		Keywords: []string{constant.FuyaoExtensionKeyword}}}
+	if !reflect.DeepEqual(charts, want) {
+		t.Errorf("ListExtensionCharts() = %+v, want %+v", charts, want)
+	}
+}
+
+func TestClientPullChart(t *testing.T) {
+	server := newTestMarketplace(t)
+	tests := []struct {
+		name    string
+		client  *Client
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"TestPull", NewClient(kubefake.NewSimpleClientset(newTestConfigMap(server.URL))), "0.1.0", "archive", false},
+		{"TestHostOverride", &Client{Host: server.URL, httpClient: http.DefaultClient}, "0.1.0", "archive", false},
+		{"TestMissingVersion", NewClient(kubefake.NewSimpleClientset(newTestConfigMap(server.URL))), "0.2.0", "",
+			true},
+		{"TestNoConfig", NewClient(kubefake.NewSimpleClientset()), "0.1.0", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := tt.client.PullChart(context.Background(), "local", "extension", tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PullChart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(archive) != tt.want {
+				t.Errorf("PullChart() = %q, want %q", archive, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientConfiguredPaths(t *testing.T) {
+	server := newTestMarketplaceAt(t, "/api/charts", "/api/local/extension-0.1.0.tgz")
+	cm := newTestConfigMap(server.URL)
+	cm.Data[configFileKey] += ChartsPathConfigKey + ": /api/charts\n" +
+		ChartDownloadPathConfigKey + ": /api/{repo}/{chart}-{version}.tgz\n"
+	client := NewClient(kubefake.NewSimpleClientset(cm))
+
+	charts, err := client.ListExtensionCharts(context.Background())
+	if err != nil || len(charts) != 1 {
+		t.Errorf("ListExtensionCharts() = %+v, %v, want the extension chart", charts, err)
+	}
+	archive, err := client.PullChart(context.Background(), "local", "extension", "0.1.0")
+	if err != nil || string(archive) != "archive" {
+		t.Errorf("PullChart() = %q, %v, want archive", archive, err)
+	}
+}
+
+func TestClientNoHost(t *testing.T) {
+	if _, err := NewClient(nil).ListExtensionCharts(context.Background()); !errors.Is(err, ErrNoHost) {
+		t.Errorf("ListExtensionCharts() error = %v, want %v", err, ErrNoHost)
+	}
+	client := NewClient(kubefake.NewSimpleClientset(newTestConfigMap("")))
+	if _, err := client.ListExtensionCharts(context.Background()); !errors.Is(err, ErrNoHost) {
+		t.Errorf("ListExtensionCharts() error = %v, want %v", err, ErrNoHost)
+	}
+}
@@ -34,11 +34,17 @@ const (
 const (
 	Success                = 200
 	FileCreated            = 201
+	Accepted               = 202
 	NoContent              = 204
 	ClientError            = 400
 	ExceedChartUploadLimit = 4001
+	Unauthorized           = 401
+	Forbidden              = 403
 	ResourceNotFound       = 404
+	Conflict               = 409
 	ServerError            = 500
+	BadGateway             = 502
+	ServiceUnavailable     = 503
 )
 
 // consoleplugin-management-service k8s component
@@ -75,5 +81,37 @@ const (
 
 // param const
 const (
-	PluginName = "pluginName"
+	PluginName      = "pluginName"
+	ResourceVersion = "resourceVersion"
+	Watch           = "watch"
+	ProxyPath       = "path"
+	Cascade         = "cascade"
+	Entrypoint      = "entrypoint"
+	Enabled         = "enabled"
+	Release         = "release"
+	LabelSelector   = "labelSelector"
+	Search          = "search"
+	SortBy          = "sortBy"
+	Limit           = "limit"
+	Continue        = "continue"
+	Lang            = "lang"
+	DryRun          = "dryRun"
+	Revision        = "revision"
 )
+
+// header const
+const (
+	ContinueHeader           = "X-Continue"
+	RemainingItemCountHeader = "X-Remaining-Item-Count"
+	ETagHeader               = "ETag"
+	IfNoneMatchHeader        = "If-None-Match"
+	IfMatchHeader            = "If-Match"
+	AcceptLanguageHeader     = "Accept-Language"
+	VaryHeader               = "Vary"
+	CacheControlHeader       = "Cache-Control"
+	AuthorizationHeader      = "Authorization"
+	CookieHeader             = "Cookie"
+)
+
+// CacheControlRevalidate lets browsers keep the responses but revalidate them with the ETag before every use
+const CacheControlRevalidate = "private, no-cache"
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	// DefaultAccessReviewTTL is how long the access review of a user is cached
+	DefaultAccessReviewTTL = 30 * time.Second
+
+	maxCachedAccessReviews = 4096
+)
+
+var accessVerbPattern = regexp.MustCompile(`^([a-z]+|\*)$`)
+
+// AccessReviewer decides whether users are allowed the access required by ConsolePlugins
+type AccessReviewer interface {
+	// Allowed returns whether the user is allowed the access
+	Allowed(ctx context.Context, user *authenticationv1.UserInfo, access *ConsolePluginAccess) (bool, error)
+}
+
+// SubjectAccessReviewer reviews the access of users with SubjectAccessReviews, the decisions are cached per user
+type SubjectAccessReviewer struct {
+	kubeClient kubernetes.Interface
+	ttl        time.Duration
+	decisions  *cache.LRUExpireCache
+}
+
+// NewSubjectAccessReviewer returns a new SubjectAccessReviewer caching the decisions for the TTL
+func NewSubjectAccessReviewer(kubeClient kubernetes.Interface, ttl time.Duration) *SubjectAccessReviewer {
+	return &SubjectAccessReviewer{
+		kubeClient: kubeClient,
+		ttl:        ttl,
+		decisions:  cache.NewLRUExpireCache(maxCachedAccessReviews),
+	}
+}
+
+// Allowed creates a SubjectAccessReview for the user unless the decision is cached
+func (r *SubjectAccessReviewer) Allowed(ctx context.Context, user *authenticationv1.UserInfo,
+	access *ConsolePluginAccess) (bool, error) {
+	key := userCacheKey(user) + "|" + access.cacheKey()
+	if allowed, ok := r.decisions.Get(key); ok {
+		return allowed.(bool), nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   access.Namespace,
+				Verb:        access.Verb,
+				Group:       access.Group,
+				Resource:    access.Resource,
+				Subresource: access.Subresource,
+				Name:        access.Name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}
+	result, err := r.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	allowed := result.Status.Allowed && !result.Status.Denied
+	r.decisions.Add(key, allowed, r.ttl)
+	return allowed, nil
+}
+
+// userCacheKey identifies the user along with the groups and the extra, which are all subject to the review
+func userCacheKey(user *authenticationv1.UserInfo) string {
+	groups := append([]string(nil), user.Groups...)
+	sort.Strings(groups)
+	extraKeys := make([]string, 0, len(user.Extra))
+	for k := range user.Extra {
+		extraKeys = append(extraKeys, k)
+	}
+	sort.Strings(extraKeys)
+	var b strings.Builder
+	b.WriteString(user.Username + "\x00" + user.UID + "\x00" + strings.Join(groups, ","))
+	for _, k := range extraKeys {
+		b.WriteString("\x00" + k + "=" + strings.Join(user.Extra[k], ","))
+	}
+	return b.String()
+}
+
+func (a *ConsolePluginAccess) cacheKey() string {
+	return strings.Join([]string{a.Verb, a.Group, a.Resource, a.Subresource, a.Namespace, a.Name}, "/")
+}
+
+// VisibilityFilter hides the ConsolePlugins and the sub-pages the user is not allowed to access.
+// A nil VisibilityFilter only shows the ConsolePlugins and the sub-pages requiring no access.
+type VisibilityFilter struct {
+	Reviewer AccessReviewer
+}
+
+// Filter returns the ConsolePlugins visible to the user, with the sub-pages which are not visible removed.
+// The required access is denied if the user is unknown or the review fails, while the ConsolePlugins
+// requiring no access are always visible.
+func (f *VisibilityFilter) Filter(ctx context.Context, user *authenticationv1.UserInfo,
+	consolePlugins []ConsolePlugin) []ConsolePlugin {
+	visible := make([]ConsolePlugin, 0, len(consolePlugins))
+	for i := range consolePlugins {
+		if cp, ok := f.FilterOne(ctx, user, &consolePlugins[i]); ok {
+			visible = append(visible, *cp)
+		}
+	}
+	return visible
+}
+
+// FilterOne returns the ConsolePlugin with the sub-pages visible to the user, false if it is not visible at all
+func (f *VisibilityFilter) FilterOne(ctx context.Context, user *authenticationv1.UserInfo,
+	cp *ConsolePlugin) (*ConsolePlugin, bool) {
+	if !f.allowed(ctx, user, cp.Name, cp.Spec.RequiredAccess) {
+		return nil, false
+	}
+	filtered := false
+	subPages := make([]ConsolePluginName, 0, len(cp.Spec.SubPages))
+	for _, page := range cp.Spec.SubPages {
+		if f.allowed(ctx, user, cp.Name+"/"+page.PageName, page.RequiredAccess) {
+			subPages = append(subPages, page)
+		} else {
+			filtered = true
+		}
+	}
+	if !filtered {
+		return cp, true
+	}
+	out := *cp
+	out.Spec.SubPages = subPages
+	return &out, true
+}
+
+func (f *VisibilityFilter) allowed(ctx context.Context, user *authenticationv1.UserInfo, name string,
+	required []ConsolePluginAccess) bool {
+	if len(required) == 0 {
+		return true
+	}
+	if user == nil || f == nil || f.Reviewer == nil {
+		return false
+	}
+	for i := range required {
+		allowed, err := f.Reviewer.Allowed(ctx, user, &required[i])
+		if err != nil {
+			zlog.Warnf("Error reviewing access of user %s to ConsolePlugin %s: %v", user.Username, name, err)
+			return false
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func validateRequiredAccess(required []ConsolePluginAccess, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, access := range required {
+		idxPath := fldPath.Index(i)
+		if access.Verb == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("verb"), ""))
+		} else if !accessVerbPattern.MatchString(access.Verb) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("verb"), access.Verb,
+				"should be a lowercase verb such as get, list or '*'"))
+		}
+		if access.Resource == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("resource"), ""))
+		}
+	}
+	return allErrs
+}
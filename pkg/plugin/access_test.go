@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+
+	"plugin-management-service/pkg/utils/testutil"
+)
+
+var (
+	testAdmin = &authenticationv1.UserInfo{Username: "admin", Groups: []string{"system:masters"}}
+	testUser  = &authenticationv1.UserInfo{Username: "user", Groups: []string{"developers"}}
+
+	clusterAccess = ConsolePluginAccess{Verb: "get", Group: "cluster.openfuyao.com", Resource: "clusters"}
+	nodeAccess    = ConsolePluginAccess{Verb: "list", Resource: "nodes"}
+)
+
+// reviewTestAccess allows the admin everything and the user to list nodes only
+func reviewTestAccess(review *authorizationv1.SubjectAccessReview) error {
+	if review.Spec.User == "broken" {
+		return errors.New("authorizer unavailable")
+	}
+	attrs := review.Spec.ResourceAttributes
+	review.Status.Allowed = review.Spec.User == "admin" ||
+		(attrs.Verb == nodeAccess.Verb && attrs.Resource == nodeAccess.Resource)
+	return nil
+}
+
+func TestSubjectAccessReviewerAllowed(t *testing.T) {
+	reviews := 0
+	reviewer := NewSubjectAccessReviewer(testutil.NewSubjectAccessReviewClient(&reviews, reviewTestAccess), time.Minute)
+	tests := []struct {
+		name        string
+		user        *authenticationv1.UserInfo
+		access      ConsolePluginAccess
+		want        bool
+		wantReviews int
+	}{
+		{"TestAdminAllowed", testAdmin, clusterAccess, true, 1},
+		{"TestAdminCached", testAdmin, clusterAccess, true, 1},
+		{"TestUserDenied", testUser, clusterAccess, false, 2},
+		{"TestUserDeniedCached", testUser, clusterAccess, false, 2},
+		{"TestUserAllowed", testUser, nodeAccess, true, 3},
+		{"TestOtherGroupsNotShared", &authenticationv1.UserInfo{Username: "user", Groups: []string{"admins"}},
+			clusterAccess, false, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reviewer.Allowed(context.Background(), tt.user, &tt.access)
+			if err != nil {
+				t.Fatalf("Allowed() error = %v", err)
+			}
+			if got != tt.want || reviews != tt.wantReviews {
+				t.Errorf("Allowed() = %v after %d reviews, want %v after %d reviews", got, reviews, tt.want,
+					tt.wantReviews)
+			}
+		})
+	}
+}
+
+func TestSubjectAccessReviewerExpiry(t *testing.T) {
+	reviews := 0
+	client := testutil.NewSubjectAccessReviewClient(&reviews, reviewTestAccess)
+	reviewer := NewSubjectAccessReviewer(client, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := reviewer.Allowed(context.Background(), testAdmin, &clusterAccess); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if reviews != 2 {
+		t.Errorf("reviews = %d, want 2 after the cache expired", reviews)
+	}
+}
+
+func TestVisibilityFilter(t *testing.T) {
+	newConsolePlugin := func(name string, required []ConsolePluginAccess, pages ...ConsolePluginName) ConsolePlugin {
+		cp := ConsolePlugin{Spec: ConsolePluginSpec{PluginName: name, RequiredAccess: required, SubPages: pages}}
+		cp.Name = name
+		return cp
+	}
+	consolePlugins := []ConsolePlugin{
+		newConsolePlugin("public", nil,
+			ConsolePluginName{PageName: "overview"},
+			ConsolePluginName{PageName: "clusters", RequiredAccess: []ConsolePluginAccess{clusterAccess}}),
+		newConsolePlugin("admin", []ConsolePluginAccess{clusterAccess, nodeAccess}),
+		newConsolePlugin("nodes", []ConsolePluginAccess{nodeAccess}),
+	}
+	reviews := 0
+	client := testutil.NewSubjectAccessReviewClient(&reviews, reviewTestAccess)
+	filter := &VisibilityFilter{Reviewer: NewSubjectAccessReviewer(client, time.Minute)}
+	tests := []struct {
+		name   string
+		filter *VisibilityFilter
+		user   *authenticationv1.UserInfo
+		want   string
+	}{
+		{"TestAdmin", filter, testAdmin, "public[overview clusters],admin[],nodes[]"},
+		{"TestUser", filter, testUser, "public[overview],nodes[]"},
+		{"TestUnknownUser", filter, nil, "public[overview]"},
+		{"TestReviewError", filter, &authenticationv1.UserInfo{Username: "broken"}, "public[overview]"},
+		{"TestNoReviewer", &VisibilityFilter{}, testAdmin, "public[overview]"},
+		{"TestNilFilter", nil, testAdmin, "public[overview]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, cp := range tt.filter.Filter(context.Background(), tt.user, consolePlugins) {
+				var pages []string
+				for _, page := range cp.Spec.SubPages {
+					pages = append(pages, page.PageName)
+				}
+				got = append(got, cp.Name+"["+strings.Join(pages, " ")+"]")
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("Filter() = %s, want %s", strings.Join(got, ","), tt.want)
+			}
+		})
+	}
+	if len(consolePlugins[0].Spec.SubPages) != 2 {
+		t.Errorf("Filter() should not modify the given ConsolePlugins")
+	}
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	// ActorAnnotation is the annotation of the Events recording who changed the ConsolePlugin
+	ActorAnnotation = "console.openfuyao.com/actor"
+
+	// SourceIPAnnotation is the annotation of the Events recording the address the change came from
+	SourceIPAnnotation = "console.openfuyao.com/source-ip"
+
+	// ForwardedForAnnotation is the annotation of the Events recording the X-Forwarded-For of the change
+	ForwardedForAnnotation = "console.openfuyao.com/forwarded-for"
+
+	// FieldsAnnotation is the annotation of the Events recording the changed fields, separated by commas
+	FieldsAnnotation = "console.openfuyao.com/fields"
+
+	// OldValueAnnotation is the annotation of the Events recording the values of the fields before the change
+	OldValueAnnotation = "console.openfuyao.com/old-value"
+
+	// NewValueAnnotation is the annotation of the Events recording the values of the fields after the change
+	NewValueAnnotation = "console.openfuyao.com/new-value"
+
+	// DefaultHistoryLimit is the default number of changes returned by History
+	DefaultHistoryLimit = 50
+
+	// MaxHistoryLimit is the maximum number of changes returned by History
+	MaxHistoryLimit = 500
+
+	auditComponent = "plugin-management-service"
+
+	// values longer than it, e.g. inline icons, are truncated
+	maxAuditValueLength = 1024
+)
+
+// ChangeAction is the kind of change made to a ConsolePlugin
+type ChangeAction string
+
+const (
+	// ChangeCreated means the ConsolePlugin was created
+	ChangeCreated ChangeAction = "Created"
+
+	// ChangeUpdated means the spec of the ConsolePlugin was replaced or patched
+	ChangeUpdated ChangeAction = "Updated"
+
+	// ChangeEnabled means the ConsolePlugin was enabled
+	ChangeEnabled ChangeAction = "Enabled"
+
+	// ChangeDisabled means the ConsolePlugin was disabled
+	ChangeDisabled ChangeAction = "Disabled"
+
+	// ChangeReordered means the order of the ConsolePlugin in the menu was changed
+	ChangeReordered ChangeAction = "Reordered"
+
+	// ChangeDeleted means the ConsolePlugin was deleted
+	ChangeDeleted ChangeAction = "Deleted"
+
+	// ChangeUninstalled means the Helm release of the ConsolePlugin was uninstalled
+	ChangeUninstalled ChangeAction = "Uninstalled"
+
+	// ChangeRolledBack means the Helm release of the ConsolePlugin was rolled back
+	ChangeRolledBack ChangeAction = "RolledBack"
+
+	// ChangeInstalled means the ConsolePlugin was installed by a chart from the marketplace
+	ChangeInstalled ChangeAction = "Installed"
+)
+
+// ChangeRecord is a change of a ConsolePlugin made through the service
+type ChangeRecord struct {
+	PluginName   string       `json:"pluginName"`
+	Action       ChangeAction `json:"action"`
+	Actor        string       `json:"actor"`
+	SourceIP     string       `json:"sourceIP,omitempty"`
+	ForwardedFor string       `json:"forwardedFor,omitempty"`
+	Fields       []string     `json:"fields,omitempty"`
+	OldValue     string       `json:"oldValue,omitempty"`
+	NewValue     string       `json:"newValue,omitempty"`
+	Time         metav1.Time  `json:"time"`
+}
+
+// message describes the change for humans, e.g. in kubectl describe
+func (r *ChangeRecord) message() string {
+	msg := fmt.Sprintf("%s %s ConsolePlugin %s", r.Actor, strings.ToLower(string(r.Action)), r.PluginName)
+	if r.SourceIP != "" {
+		msg += " from " + r.SourceIP
+	}
+	if len(r.Fields) > 0 {
+		msg += fmt.Sprintf(": %s %s -> %s", strings.Join(r.Fields, ","), r.OldValue, r.NewValue)
+	}
+	return msg
+}
+
+// AuditRecorder records the changes of ConsolePlugins as Kubernetes Events on them and in the audit log.
+// The Events are kept by the API server for its event TTL only, the audit log is the long-term trail.
+type AuditRecorder struct {
+	kubeClient kubernetes.Interface
+
+	// Namespace is where the Events are created as ConsolePlugins are cluster scoped
+	Namespace string
+
+	now func() time.Time
+}
+
+// NewAuditRecorder returns a new AuditRecorder creating the Events in the default namespace
+func NewAuditRecorder(kubeClient kubernetes.Interface) *AuditRecorder {
+	return &AuditRecorder{
+		kubeClient: kubeClient,
+		Namespace:  metav1.NamespaceDefault,
+		now:        time.Now,
+	}
+}
+
+// Record writes the change to the audit log and creates an Event for it. Failing to create the Event is
+// logged only, the change has been made already. Nothing is recorded by a nil AuditRecorder.
+func (r *AuditRecorder) Record(ctx context.Context, record *ChangeRecord) {
+	if r == nil {
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = metav1.NewTime(r.now())
+	}
+	record.OldValue = truncateAuditValue(record.OldValue)
+	record.NewValue = truncateAuditValue(record.NewValue)
+	zlog.Audit("ConsolePlugin changed",
+		"pluginName", record.PluginName,
+		"action", record.Action,
+		"actor", record.Actor,
+		"sourceIP", record.SourceIP,
+		"forwardedFor", record.ForwardedFor,
+		"fields", record.Fields,
+		"oldValue", record.OldValue,
+		"newValue", record.NewValue,
+		"time", record.Time.UTC().Format(time.RFC3339Nano),
+	)
+	if r.kubeClient == nil {
+		return
+	}
+
+	// the Event must not be lost when the client goes away after the change
+	ctx = context.WithoutCancel(ctx)
+	event := r.newEvent(record)
+	if _, err := r.kubeClient.CoreV1().Events(r.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		zlog.Errorf("Error creating Event for ConsolePlugin %s: %v", record.PluginName, err)
+	}
+}
+
+func (r *AuditRecorder) newEvent(record *ChangeRecord) *corev1.Event {
+	annotations := map[string]string{
+		ActorAnnotation: record.Actor,
+	}
+	setAnnotation := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	setAnnotation(SourceIPAnnotation, record.SourceIP)
+	setAnnotation(ForwardedForAnnotation, record.ForwardedFor)
+	setAnnotation(FieldsAnnotation, strings.Join(record.Fields, ","))
+	setAnnotation(OldValueAnnotation, record.OldValue)
+	setAnnotation(NewValueAnnotation, record.NewValue)
+
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s.%x", record.PluginName, record.Time.UnixNano()),
+			Namespace:   r.Namespace,
+			Annotations: annotations,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: consolePluginGVR.GroupVersion().String(),
+			Kind:       consolePluginKind,
+			Name:       record.PluginName,
+		},
+		Reason:              string(record.Action),
+		Message:             record.message(),
+		Source:              corev1.EventSource{Component: auditComponent},
+		FirstTimestamp:      record.Time,
+		LastTimestamp:       record.Time,
+		Count:               1,
+		Type:                corev1.EventTypeNormal,
+		ReportingController: auditComponent,
+	}
+}
+
+// History returns at most limit recent changes of the ConsolePlugin with given name from its Events, the latest
+// first. Changes of a deleted ConsolePlugin are returned as well while their Events are kept.
+func (r *AuditRecorder) History(ctx context.Context, pluginName string, limit int) ([]ChangeRecord, error) {
+	if limit <= 0 || limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+	selector := fields.Set{
+		"involvedObject.kind": consolePluginKind,
+		"involvedObject.name": pluginName,
+		"source":              auditComponent,
+	}.AsSelector()
+	events, err := r.kubeClient.CoreV1().Events(r.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]ChangeRecord, 0, len(events.Items))
+	for i := range events.Items {
+		event := &events.Items[i]
+		// the selector is not honored by every client, and the Events of others must be skipped
+		if event.InvolvedObject.Kind != consolePluginKind || event.InvolvedObject.Name != pluginName ||
+			event.Source.Component != auditComponent {
+			continue
+		}
+		if _, ok := event.Annotations[ActorAnnotation]; !ok {
+			continue
+		}
+		records = append(records, changeRecordFromEvent(event))
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[j].Time.Before(&records[i].Time)
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func changeRecordFromEvent(event *corev1.Event) ChangeRecord {
+	record := ChangeRecord{
+		PluginName:   event.InvolvedObject.Name,
+		Action:       ChangeAction(event.Reason),
+		Actor:        event.Annotations[ActorAnnotation],
+		SourceIP:     event.Annotations[SourceIPAnnotation],
+		ForwardedFor: event.Annotations[ForwardedForAnnotation],
+		OldValue:     event.Annotations[OldValueAnnotation],
+		NewValue:     event.Annotations[NewValueAnnotation],
+		Time:         event.LastTimestamp,
+	}
+	if changedFields := event.Annotations[FieldsAnnotation]; changedFields != "" {
+		record.Fields = strings.Split(changedFields, ",")
+	}
+	return record
+}
+
+// DiffConsolePluginSpec returns the changed fields of the spec, and their values before and after the change.
+// The values are in JSON, of the only field if a single one is changed, or an object of the changed fields.
+func DiffConsolePluginSpec(old, updated *ConsolePluginSpec) ([]string, string, string) {
+	oldFields, err := specFields(old)
+	if err != nil {
+		return nil, "", ""
+	}
+	newFields, err := specFields(updated)
+	if err != nil {
+		return nil, "", ""
+	}
+
+	var keys []string
+	for key, value := range oldFields {
+		if string(value) != string(newFields[key]) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, "", ""
+	}
+	sort.Strings(keys)
+
+	changedFields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		changedFields = append(changedFields, "spec."+key)
+	}
+	if len(keys) == 1 {
+		return changedFields, string(oldFields[keys[0]]), string(newFields[keys[0]])
+	}
+	return changedFields, subsetJSON(oldFields, keys), subsetJSON(newFields, keys)
+}
+
+func specFields(spec *ConsolePluginSpec) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	specFields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &specFields); err != nil {
+		return nil, err
+	}
+	return specFields, nil
+}
+
+func subsetJSON(values map[string]json.RawMessage, keys []string) string {
+	subset := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			subset[key] = value
+		}
+	}
+	data, err := json.Marshal(subset)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func truncateAuditValue(value string) string {
+	if len(value) <= maxAuditValueLength {
+		return value
+	}
+	return strings.ToValidUTF8(value[:maxAuditValueLength], "") + "..."
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAuditRecorderRecord(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	recorder := NewAuditRecorder(client)
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	recorder.now = func() time.Time { return now }
+
+	recorder.Record(context.Background(), &ChangeRecord{
+		PluginName: "test-plugin",
+		Action:     ChangeDisabled,
+		Actor:      "admin",
+		SourceIP:   "10.0.0.1",
+		Fields:     []string{"spec.enabled"},
+		OldValue:   "true",
+		NewValue:   "false",
+	})
+
+	events, err := client.CoreV1().Events(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("Events = %d, want 1", len(events.Items))
+	}
+	event := events.Items[0]
+	if event.InvolvedObject.Kind != consolePluginKind || event.InvolvedObject.Name != "test-plugin" ||
+		event.Reason != string(ChangeDisabled) || event.Type != corev1.EventTypeNormal {
+		t.Errorf("Event = %+v, want a Normal Disabled Event of ConsolePlugin test-plugin", event)
+	}
+	wantMessage := "admin disabled ConsolePlugin test-plugin from 10.0.0.1: spec.enabled true -> false"
+	if event.Message != wantMessage {
+		t.Errorf("Event message = %q, want %q", event.Message, wantMessage)
+	}
+	if event.Annotations[ActorAnnotation] != "admin" || event.Annotations[OldValueAnnotation] != "true" ||
+		event.Annotations[NewValueAnnotation] != "false" || !event.LastTimestamp.Time.Equal(now) {
+		t.Errorf("Event annotations = %v, time %v", event.Annotations, event.LastTimestamp)
+	}
+
+	// nil recorders record nothing
+	var nilRecorder *AuditRecorder
+	nilRecorder.Record(context.Background(), &ChangeRecord{PluginName: "test-plugin"})
+}
+
+func TestAuditRecorderHistory(t *testing.T) {
+	client := kubefake.NewSimpleClientset(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "test-plugin.other", Namespace: metav1.NamespaceDefault},
+		InvolvedObject: corev1.ObjectReference{Kind: consolePluginKind, Name: "test-plugin"},
+		Reason:         "Synced",
+		Source:         corev1.EventSource{Component: "other-controller"},
+	})
+	recorder := NewAuditRecorder(client)
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	actions := []ChangeAction{ChangeCreated, ChangeDisabled, ChangeEnabled, ChangeReordered}
+	for i, action := range actions {
+		recorder.Record(context.Background(), &ChangeRecord{
+			PluginName: "test-plugin",
+			Action:     action,
+			Actor:      "admin",
+			Time:       metav1.NewTime(start.Add(time.Duration(i) * time.Minute)),
+		})
+	}
+	recorder.Record(context.Background(), &ChangeRecord{
+		PluginName: "other-plugin",
+		Action:     ChangeDeleted,
+		Actor:      "admin",
+		Time:       metav1.NewTime(start),
+	})
+
+	tests := []struct {
+		name       string
+		pluginName string
+		limit      int
+		want       string
+	}{
+		{"TestLatestFirst", "test-plugin", DefaultHistoryLimit, "Reordered,Enabled,Disabled,Created"},
+		{"TestLimit", "test-plugin", 2, "Reordered,Enabled"},
+		{"TestOtherPlugin", "other-plugin", DefaultHistoryLimit, "Deleted"},
+		{"TestNoHistory", "unknown-plugin", DefaultHistoryLimit, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := recorder.History(context.Background(), tt.pluginName, tt.limit)
+			if err != nil {
+				t.Fatalf("History() error = %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				if record.PluginName != tt.pluginName || record.Actor != "admin" {
+					t.Errorf("History() record = %+v", record)
+				}
+				got = append(got, string(record.Action))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("History() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffConsolePluginSpec(t *testing.T) {
+	order := int64(3)
+	tests := []struct {
+		name       string
+		mutate     func(spec *ConsolePluginSpec)
+		wantFields string
+		wantOld    string
+		wantNew    string
+	}{
+		{"TestUnchanged", func(spec *ConsolePluginSpec) {}, "", "", ""},
+		{"TestSingleField", func(spec *ConsolePluginSpec) { spec.Enabled = false }, "spec.enabled", "true", "false"},
+		{"TestAddedField", func(spec *ConsolePluginSpec) { spec.Order = &order }, "spec.order", "", "3"},
+		{
+			"TestMultipleFields",
+			func(spec *ConsolePluginSpec) {
+				spec.Enabled = false
+				spec.DisplayName = "Renamed"
+			},
+			"spec.displayName,spec.enabled",
+			`{"displayName":"Test Plugin","enabled":true}`,
+			`{"displayName":"Renamed","enabled":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newValidTestSpec()
+			updated := newValidTestSpec()
+			tt.mutate(&updated)
+			fields, oldValue, newValue := DiffConsolePluginSpec(&old, &updated)
+			if strings.Join(fields, ",") != tt.wantFields || oldValue != tt.wantOld || newValue != tt.wantNew {
+				t.Errorf("DiffConsolePluginSpec() = %v, %s, %s, want %s, %s, %s", fields, oldValue, newValue,
+					tt.wantFields, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestTruncateAuditValue(t *testing.T) {
+	long := strings.Repeat("扩", maxAuditValueLength)
+	got := truncateAuditValue(long)
+	if len(got) > maxAuditValueLength+len("...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("truncateAuditValue() length = %d", len(got))
+	}
+	if got = truncateAuditValue("true"); got != "true" {
+		t.Errorf("truncateAuditValue() = %s, want true", got)
+	}
+}
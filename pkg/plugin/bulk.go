@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+// DefaultBulkWorkers is the default number of ConsolePlugins whose enablement is set in parallel
+const DefaultBulkWorkers = 4
+
+// EnablementResultStatus is the outcome of setting the enablement of a single ConsolePlugin in bulk
+type EnablementResultStatus string
+
+const (
+	// EnablementChanged means the enablement of the ConsolePlugin was changed
+	EnablementChanged EnablementResultStatus = "Changed"
+
+	// EnablementUnchanged means the ConsolePlugin already had the expected enablement
+	EnablementUnchanged EnablementResultStatus = "Unchanged"
+
+	// EnablementNotFound means the ConsolePlugin is not installed
+	EnablementNotFound EnablementResultStatus = "NotFound"
+
+	// EnablementError means the enablement could not be set, e.g. refused by the dependency checks
+	EnablementError EnablementResultStatus = "Error"
+)
+
+// EnablementItem is the expected enablement of a ConsolePlugin
+type EnablementItem struct {
+	PluginName string `json:"pluginName"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// EnablementResult is the result of setting the enablement of a ConsolePlugin in bulk
+type EnablementResult struct {
+	PluginName string                 `json:"pluginName"`
+	Enabled    bool                   `json:"enabled"`
+	Status     EnablementResultStatus `json:"status"`
+	Message    string                 `json:"message,omitempty"`
+}
+
+// SetPluginEnablementBulk sets the enablement of the ConsolePlugins with at most the given number of
+// workers in parallel. A result is returned for every item in the same order, the failure of an item
+// does not stop the others.
+//
+// The dependencies are checked once against the enablement of every ConsolePlugin after the whole batch,
+// so a ConsolePlugin and its dependencies could be enabled or disabled together. The items refused by the
+// checks are left unchanged, which may in turn refuse the items depending on them. The changes are then
+// applied in topological order, the dependents being disabled first and the dependencies being enabled first.
+func (cm *ConsolePluginManager) SetPluginEnablementBulk(ctx context.Context, items []EnablementItem,
+	workers int) []EnablementResult {
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+	results := make([]EnablementResult, len(items))
+	for i, item := range items {
+		results[i] = EnablementResult{PluginName: item.PluginName, Enabled: item.Enabled}
+	}
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		for i := range results {
+			results[i].Status = EnablementError
+			results[i].Message = err.Error()
+		}
+		return results
+	}
+
+	b := newBulkEnablement(cm, newDependencyGraph(consolePlugins), items)
+	b.check()
+	b.apply(ctx, workers, false)
+	b.apply(ctx, workers, true)
+
+	for i, item := range items {
+		result := &results[i]
+		err, failed := b.errs[item.PluginName]
+		switch {
+		case apierrors.IsNotFound(err):
+			result.Status = EnablementNotFound
+			result.Message = err.Error()
+		case failed:
+			result.Status = EnablementError
+			result.Message = err.Error()
+		case b.applied[item.PluginName]:
+			result.Status = EnablementChanged
+		case b.graph[item.PluginName].Spec.Enabled == item.Enabled:
+			result.Status = EnablementUnchanged
+		default:
+			// skipped because the context is done
+			result.Status = EnablementError
+			result.Message = fmt.Sprintf("not changed: %v", ctx.Err())
+		}
+	}
+	return results
+}
+
+// bulkEnablement plans and applies the enablement of ConsolePlugins in bulk
+type bulkEnablement struct {
+	cm    *ConsolePluginManager
+	graph dependencyGraph
+
+	// changes are the ConsolePlugins whose enablement is to be changed, to the value mapped
+	changes map[string]bool
+	// target is the enablement of every ConsolePlugin once the changes are applied
+	target map[string]bool
+	// errs are the errors of the ConsolePlugins which are not changed
+	errs map[string]error
+	// applied are the ConsolePlugins whose enablement was changed
+	applied map[string]bool
+}
+
+func newBulkEnablement(cm *ConsolePluginManager, g dependencyGraph, items []EnablementItem) *bulkEnablement {
+	b := &bulkEnablement{
+		cm:      cm,
+		graph:   g,
+		changes: map[string]bool{},
+		target:  make(map[string]bool, len(g)),
+		errs:    map[string]error{},
+		applied: map[string]bool{},
+	}
+	for name, cp := range g {
+		b.target[name] = cp.Spec.Enabled
+	}
+	expected := make(map[string]bool, len(items))
+	for _, item := range items {
+		name := item.PluginName
+		cp, ok := g[name]
+		if !ok {
+			b.errs[name] = apierrors.NewNotFound(consolePluginGVR.GroupResource(), name)
+			continue
+		}
+		if enabled, listed := expected[name]; listed && enabled != item.Enabled {
+			b.errs[name] = apierrors.NewBadRequest(fmt.Sprintf("%s is listed with both enabled and disabled", name))
+			delete(b.changes, name)
+			b.target[name] = cp.Spec.Enabled
+			continue
+		}
+		expected[name] = item.Enabled
+		if _, failed := b.errs[name]; !failed && cp.Spec.Enabled != item.Enabled {
+			b.changes[name] = item.Enabled
+			b.target[name] = item.Enabled
+		}
+	}
+	return b
+}
+
+// check refuses the changes which would break the dependencies once the batch is applied, until the
+// remaining changes are all consistent
+func (b *bulkEnablement) check() {
+	for refused := true; refused; {
+		refused = false
+		for _, name := range b.changeNames() {
+			if err := b.checkChange(name, b.changes[name]); err != nil {
+				b.errs[name] = err
+				delete(b.changes, name)
+				b.target[name] = !b.target[name]
+				refused = true
+			}
+		}
+	}
+}
+
+func (b *bulkEnablement) checkChange(name string, enabled bool) error {
+	cp := b.graph[name]
+	if enabled {
+		if err := b.cm.CheckCompatibility(cp); err != nil {
+			return newDependencyConflict(name, err)
+		}
+		if _, _, err := b.graph.dependencyOrder(name); err != nil {
+			return newDependencyConflict(name, err)
+		}
+		var disabled []string
+		for _, dependency := range cp.Spec.Dependencies {
+			if !b.target[dependency] {
+				disabled = append(disabled, dependency)
+			}
+		}
+		if len(disabled) > 0 {
+			return newDependencyConflict(name,
+				fmt.Errorf("dependencies %v are missing or disabled, enable them in the same batch or first", disabled))
+		}
+		return nil
+	}
+
+	if _, err := b.graph.dependentOrder(name); err != nil {
+		return newDependencyConflict(name, err)
+	}
+	var enabledDependents []string
+	for _, dependent := range b.graph.dependents(name) {
+		if b.target[dependent] {
+			enabledDependents = append(enabledDependents, dependent)
+		}
+	}
+	if len(enabledDependents) > 0 {
+		return newDependencyConflict(name,
+			fmt.Errorf("enabled plugins %v depend on it, disable them in the same batch or first", enabledDependents))
+	}
+	return nil
+}
+
+func (b *bulkEnablement) changeNames() []string {
+	names := make([]string, 0, len(b.changes))
+	for name := range b.changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// prerequisites returns the changes which must be applied before the change of the ConsolePlugin: the
+// dependencies being enabled, or the dependents being disabled
+func (b *bulkEnablement) prerequisites(name string) []string {
+	enabled := b.changes[name]
+	candidates := b.graph.dependents(name)
+	if enabled {
+		candidates = b.graph[name].Spec.Dependencies
+	}
+	var names []string
+	for _, candidate := range candidates {
+		if e, ok := b.changes[candidate]; ok && e == enabled {
+			names = append(names, candidate)
+		}
+	}
+	return names
+}
+
+// levels groups the changes to the given enablement by their depth in the dependency graph, every change
+// coming after its prerequisites. The checks made sure the prerequisites have no cycle.
+func (b *bulkEnablement) levels(enabled bool) [][]string {
+	depths := map[string]int{}
+	var depth func(name string) int
+	depth = func(name string) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		d := 0
+		for _, prerequisite := range b.prerequisites(name) {
+			if pd := depth(prerequisite) + 1; pd > d {
+				d = pd
+			}
+		}
+		depths[name] = d
+		return d
+	}
+	var levels [][]string
+	for _, name := range b.changeNames() {
+		if b.changes[name] != enabled {
+			continue
+		}
+		d := depth(name)
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], name)
+	}
+	return levels
+}
+
+// apply patches the changes to the given enablement level by level, the ConsolePlugins of a level in
+// parallel. A change is not applied if any of its prerequisites failed.
+func (b *bulkEnablement) apply(ctx context.Context, workers int, enabled bool) {
+	for _, level := range b.levels(enabled) {
+		errs := make([]error, len(level))
+		done := make([]bool, len(level))
+		workqueue.ParallelizeUntil(ctx, workers, len(level), func(i int) {
+			name := level[i]
+			done[i] = true
+			for _, prerequisite := range b.prerequisites(name) {
+				if !b.applied[prerequisite] {
+					errs[i] = newDependencyConflict(name,
+						fmt.Errorf("not changed since the enablement of %s could not be changed", prerequisite))
+					return
+				}
+			}
+			patch := []byte(fmt.Sprintf(`{"spec": {"enabled": %t}}`, enabled))
+			if errs[i] = PatchConsolePlugin(b.cm.Client, name, patch); errs[i] == nil {
+				zlog.Infof("Set ConsolePlugin %s enablement to %t", name, enabled)
+			}
+		})
+		for i, name := range level {
+			switch {
+			case errs[i] != nil:
+				b.errs[name] = errs[i]
+			case done[i]:
+				b.applied[name] = true
+			}
+		}
+	}
+}
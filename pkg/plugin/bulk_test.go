@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSetPluginEnablementBulk(t *testing.T) {
+	cm := newTestManager(withEnabled(testDependencyGraph, "a", "b")...)
+	items := []EnablementItem{
+		{PluginName: "c", Enabled: true},
+		{PluginName: "b", Enabled: true},
+		{PluginName: "not-installed", Enabled: true},
+		{PluginName: "x", Enabled: true},
+		{PluginName: "d", Enabled: true},
+	}
+	want := []EnablementResultStatus{
+		EnablementChanged,
+		EnablementUnchanged,
+		EnablementNotFound,
+		EnablementError,
+		EnablementChanged,
+	}
+
+	results := cm.SetPluginEnablementBulk(context.Background(), items, 2)
+	if len(results) != len(items) {
+		t.Fatalf("SetPluginEnablementBulk() returned %d results, want %d", len(results), len(items))
+	}
+	for i, result := range results {
+		if result.PluginName != items[i].PluginName || result.Status != want[i] {
+			t.Errorf("result[%d] = %+v, want %s %s", i, result, items[i].PluginName, want[i])
+		}
+		if (result.Message != "") != (want[i] == EnablementNotFound || want[i] == EnablementError) {
+			t.Errorf("result[%d] message = %q", i, result.Message)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range cm.SetPluginEnablementBulk(ctx, items, 2) {
+		if result.Status != EnablementError {
+			t.Errorf("result of a cancelled bulk = %+v, want error", result)
+		}
+	}
+}
+
+func TestListConsolePluginsBySelector(t *testing.T) {
+	cm := newTestManager(
+		testConsolePlugin{name: "labeled", labels: map[string]string{"category": "monitoring"}},
+		testConsolePlugin{name: "unlabeled"},
+	)
+
+	selector, err := labels.Parse("category=monitoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	consolePlugins, err := cm.ListConsolePluginsBySelector(selector)
+	if err != nil || len(consolePlugins) != 1 || consolePlugins[0].Name != "labeled" {
+		t.Errorf("ListConsolePluginsBySelector() = %v, %v", consolePlugins, err)
+	}
+}
+
+func TestSetPluginEnablementBulkTogether(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager *ConsolePluginManager
+		items   []EnablementItem
+		want    []EnablementResultStatus
+	}{
+		{
+			"TestEnableWithDependencies",
+			newTestManager(testDependencyGraph...),
+			[]EnablementItem{{"c", true}, {"b", true}, {"a", true}},
+			[]EnablementResultStatus{EnablementChanged, EnablementChanged, EnablementChanged},
+		},
+		{
+			"TestDisableWithDependents",
+			newTestManager(withEnabled(testDependencyGraph, "a", "b", "c")...),
+			[]EnablementItem{{"a", false}, {"b", false}, {"c", false}},
+			[]EnablementResultStatus{EnablementChanged, EnablementChanged, EnablementChanged},
+		},
+		{
+			"TestRefusalPropagates",
+			newTestManager(testDependencyGraph...),
+			[]EnablementItem{{"c", true}, {"b", true}},
+			[]EnablementResultStatus{EnablementError, EnablementError},
+		},
+		{
+			"TestDisableDependencyOfKeptDependent",
+			newTestManager(withEnabled(testDependencyGraph, "a", "b", "c")...),
+			[]EnablementItem{{"a", false}, {"b", false}},
+			[]EnablementResultStatus{EnablementError, EnablementError},
+		},
+		{
+			"TestContradictoryItems",
+			newTestManager(withEnabled(testDependencyGraph, "a")...),
+			[]EnablementItem{{"b", true}, {"b", false}},
+			[]EnablementResultStatus{EnablementError, EnablementError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := tt.manager.SetPluginEnablementBulk(context.Background(), tt.items, 2)
+			for i, result := range results {
+				if result.Status != tt.want[i] {
+					t.Errorf("result[%d] = %+v, want %s", i, result, tt.want[i])
+				}
+			}
+			for i, item := range tt.items {
+				cp, err := GetConsolePlugin(tt.manager.Client, item.PluginName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if changed := tt.want[i] == EnablementChanged; changed && cp.Spec.Enabled != item.Enabled {
+					t.Errorf("%s enabled = %t, want %t", item.PluginName, cp.Spec.Enabled, item.Enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkEnablementLevels(t *testing.T) {
+	cm := newTestManager(testDependencyGraph...)
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newBulkEnablement(cm, newDependencyGraph(consolePlugins),
+		[]EnablementItem{{"d", true}, {"c", true}, {"b", true}, {"a", true}})
+	b.check()
+	if got := fmt.Sprint(b.levels(true)); got != "[[a] [b d] [c]]" {
+		t.Errorf("levels() = %s, want dependencies first", got)
+	}
+}
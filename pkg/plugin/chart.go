@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/utils/util"
+)
+
+// consolePluginTemplatePattern matches the kind of a ConsolePlugin in a template, before it is rendered
+var consolePluginTemplatePattern = regexp.MustCompile(`(?m)^kind:\s*["']?` + consolePluginKind + `["']?\s*$`)
+
+// ValidateExtensionChart checks that the chart is a console extension, tagged with the openfuyao-extension keyword
+// and with a template of ConsolePlugin. A BadRequest error is returned otherwise.
+func ValidateExtensionChart(ch *chart.Chart) error {
+	if ch.Metadata == nil {
+		return apierrors.NewBadRequest("chart has no metadata")
+	}
+	if !util.Contains(ch.Metadata.Keywords, constant.FuyaoExtensionKeyword) {
+		return apierrors.NewBadRequest(fmt.Sprintf("chart %s is not tagged with the %s keyword",
+			ch.Metadata.Name, constant.FuyaoExtensionKeyword))
+	}
+	if !hasConsolePluginTemplate(ch) {
+		return apierrors.NewBadRequest(fmt.Sprintf("chart %s has no template of %s", ch.Metadata.Name,
+			consolePluginKind))
+	}
+	return nil
+}
+
+// hasConsolePluginTemplate returns whether the chart or one of its subcharts has a template of ConsolePlugin
+func hasConsolePluginTemplate(ch *chart.Chart) bool {
+	for _, template := range ch.Templates {
+		if consolePluginTemplatePattern.Match(template.Data) {
+			return true
+		}
+	}
+	for _, dependency := range ch.Dependencies() {
+		if hasConsolePluginTemplate(dependency) {
+			return true
+		}
+	}
+	return false
+}
+
+// consolePluginsInManifest returns the names of the ConsolePlugins in the rendered manifest of a release
+func consolePluginsInManifest(manifest string) []string {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var names []string
+	for _, key := range keys {
+		doc := docs[key]
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil {
+			continue
+		}
+		if head.Kind == consolePluginKind && head.APIVersion == consolePluginGVR.GroupVersion().String() {
+			names = append(names, head.Metadata.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"plugin-management-service/pkg/constant"
+)
+
+func TestValidateExtensionChart(t *testing.T) {
+	withSubchart := newTestExtensionChart(constant.FuyaoExtensionKeyword)
+	subchart := withSubchart.Templates
+	withSubchart.Templates = nil
+	withSubchart.AddDependency(&chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "0.1.0"},
+		Templates: subchart,
+	})
+	noTemplate := newTestExtensionChart(constant.FuyaoExtensionKeyword)
+	noTemplate.Templates = []*chart.File{{Name: "templates/service.yaml", Data: []byte("kind: Service\n")}}
+
+	tests := []struct {
+		name    string
+		chart   *chart.Chart
+		wantErr bool
+	}{
+		{"TestExtension", newTestExtensionChart(constant.FuyaoExtensionKeyword), false},
+		{"TestSubchartTemplate", withSubchart, false},
+		{"TestNoKeyword", newTestExtensionChart(), true},
+		{"TestNoTemplate", noTemplate, true},
+		{"TestNoMetadata", &chart.Chart{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtensionChart(tt.chart)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateExtensionChart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsBadRequest(err) {
+				t.Errorf("ValidateExtensionChart() error = %v, want BadRequest", err)
+			}
+		})
+	}
+}
+
+func TestConsolePluginsInManifest(t *testing.T) {
+	manifest := `---
+# Source: extension/templates/consoleplugin.yaml
+apiVersion: console.openfuyao.com/v1beta1
+kind: ConsolePlugin
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: backend
+---
+apiVersion: console.openfuyao.com/v1beta1
+kind: ConsolePlugin
+metadata:
+  name: second
+`
+	want := []string{"first", "second"}
+	if got := consolePluginsInManifest(manifest); !reflect.DeepEqual(got, want) {
+		t.Errorf("consolePluginsInManifest() = %v, want %v", got, want)
+	}
+}
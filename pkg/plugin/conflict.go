@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+)
+
+// CauseTypeDependencyConflict marks the conflicts caused by the dependencies between ConsolePlugins,
+// which would not be resolved by retrying
+const CauseTypeDependencyConflict = "DependencyConflict"
+
+func newResourceVersionConflict(name, expected, actual string) *apierrors.StatusError {
+	return apierrors.NewConflict(consolePluginGVR.GroupResource(), name,
+		fmt.Errorf("the object has been modified, resourceVersion is %s instead of %s", actual, expected))
+}
+
+// IsDependencyConflict returns whether the error is a conflict caused by the dependencies between ConsolePlugins
+func IsDependencyConflict(err error) bool {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || !apierrors.IsConflict(err) || statusErr.Status().Details == nil {
+		return false
+	}
+	for _, cause := range statusErr.Status().Details.Causes {
+		if cause.Type == CauseTypeDependencyConflict {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetriableConflict returns whether the error is a conflict of resourceVersions which could be resolved by
+// reading the latest ConsolePlugin again
+func isRetriableConflict(err error) bool {
+	return apierrors.IsConflict(err) && !IsDependencyConflict(err)
+}
+
+// RetryOnConflict runs fn again with backoff as long as it fails with a resourceVersion conflict.
+// fn is expected to read the latest ConsolePlugin and write it back with its resourceVersion as precondition.
+func RetryOnConflict(fn func() error) error {
+	return retry.OnError(retry.DefaultRetry, isRetriableConflict, fn)
+}
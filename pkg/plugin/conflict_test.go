@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// testVersionedPlugins are the disabled ConsolePlugins b -> a with known resourceVersions
+var testVersionedPlugins = []testConsolePlugin{
+	{name: "a", disabled: true, resourceVersion: "10"},
+	{name: "b", disabled: true, dependencies: []string{"a"}, resourceVersion: "20"},
+}
+
+func TestSetPluginEnablementIfMatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		pluginName      string
+		cascade         bool
+		resourceVersion string
+		wantChanged     []string
+		wantConflict    bool
+	}{
+		{"TestNoPrecondition", "a", false, "", []string{"a"}, false},
+		{"TestMatch", "a", false, "10", []string{"a"}, false},
+		{"TestMismatch", "a", false, "9", nil, true},
+		{"TestCascadeMatch", "b", true, "20", []string{"a", "b"}, false},
+		{"TestCascadeMismatchChangesNothing", "b", true, "10", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestManager(testVersionedPlugins...)
+			changed, err := cm.SetPluginEnablementIfMatch(tt.pluginName, true, tt.cascade, tt.resourceVersion)
+			if tt.wantConflict {
+				if !apierrors.IsConflict(err) || IsDependencyConflict(err) {
+					t.Fatalf("SetPluginEnablementIfMatch() error = %v, want resourceVersion conflict", err)
+				}
+				if enabled, _ := cm.CheckPluginEnablementIfInstalled("a"); enabled {
+					t.Errorf("nothing should be changed on conflict")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPluginEnablementIfMatch() error = %v", err)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestSetPluginEnablementIfMatchPatchPrecondition(t *testing.T) {
+	cm := newTestManager(testVersionedPlugins...)
+	var patches []string
+	client := cm.Client.(interface {
+		PrependReactor(verb, resource string, reaction clienttesting.ReactionFunc)
+	})
+	client.PrependReactor("patch", "consoleplugins", func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+		patch := string(action.(clienttesting.PatchAction).GetPatch())
+		patches = append(patches, patch)
+		if strings.Contains(patch, `"resourceVersion"`) {
+			// modified by someone else between the read and the patch
+			return true, nil, apierrors.NewConflict(consolePluginGVR.GroupResource(), "a",
+				fmt.Errorf("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	_, err := cm.SetPluginEnablementIfMatch("a", true, false, "10")
+	if !apierrors.IsConflict(err) {
+		t.Fatalf("SetPluginEnablementIfMatch() error = %v, want conflict", err)
+	}
+	if len(patches) != 1 || !strings.Contains(patches[0], `"resourceVersion": "10"`) {
+		t.Errorf("patches = %v, want the precondition in the patch", patches)
+	}
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := apierrors.NewConflict(consolePluginGVR.GroupResource(), "a", errors.New("modified"))
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"TestSuccess", []error{nil}, 1, false},
+		{"TestRetryConflict", []error{conflict, conflict, nil}, 3, false},
+		{"TestNoRetryDependencyConflict", []error{newDependencyConflict("a", errors.New("cycle")), nil}, 1, true},
+		{"TestNoRetryOtherError", []error{errors.New("broken"), nil}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := RetryOnConflict(func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if (err != nil) != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("RetryOnConflict() error = %v after %d calls, want error %v after %d calls",
+					err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+}
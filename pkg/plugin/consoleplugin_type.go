@@ -26,6 +26,10 @@ type ConsolePluginSpec struct {
 	// DisplayName is the display name of the consoleplugin on the UI entrypoint, should be between 1 and 128 characters.
 	DisplayName string `json:"displayName"`
 
+	// DisplayNames are the display names keyed by BCP-47 locale, e.g. "zh-CN" and "en".
+	// DisplayName is displayed for the locales not listed.
+	DisplayNames map[string]string `json:"displayNames,omitempty"`
+
 	// SubPages stands for the pages under the main console consoleplugin. Only applicable for "Side" Entrypoint
 	SubPages []ConsolePluginName `json:"subPages,omitempty"`
 
@@ -39,6 +43,20 @@ type ConsolePluginSpec struct {
 	// Enabled specifies whether the consoleplugin would be loaded on console webpage.
 	// Default tto be true (would be loaded)
 	Enabled bool `json:"enabled"`
+
+	// Icon is the icon rendered with the entrypoint of the consoleplugin
+	Icon *ConsolePluginIcon `json:"icon,omitempty"`
+
+	// RequiredAccess are the resource attributes which the console user must be allowed, all of them,
+	// to see the consoleplugin. The consoleplugin is visible to every user if empty.
+	RequiredAccess []ConsolePluginAccess `json:"requiredAccess,omitempty"`
+
+	// Dependencies are the names of the consoleplugins which must be enabled for this consoleplugin to work
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// ConsoleVersion is the range of console versions supported by the consoleplugin, e.g. ">=1.2 <2.0".
+	// The consoleplugin is supposed to support all console versions if empty.
+	ConsoleVersion string `json:"consoleVersion,omitempty"`
 }
 
 // ConsolePluginName is the name of the consoleplugin
@@ -48,6 +66,58 @@ type ConsolePluginName struct {
 
 	// DisplayName is the display name of the consoleplugin on the UI entrypoint, should be between 1 and 128 characters.
 	DisplayName string `json:"displayName"`
+
+	// DisplayNames are the display names keyed by BCP-47 locale, DisplayName is displayed for the locales not listed
+	DisplayNames map[string]string `json:"displayNames,omitempty"`
+
+	// RequiredAccess are the resource attributes which the console user must be allowed to see the page
+	RequiredAccess []ConsolePluginAccess `json:"requiredAccess,omitempty"`
+}
+
+// ConsolePluginAccess is an access to kubernetes resources, reviewed with SubjectAccessReviews
+type ConsolePluginAccess struct {
+	// Verb is the kubernetes verb, e.g. get, list or '*'
+	Verb string `json:"verb"`
+
+	// Group is the API group of the resource, empty for the core group
+	Group string `json:"group,omitempty"`
+
+	// Resource is the resource type, e.g. clusters
+	Resource string `json:"resource"`
+
+	// Subresource is the subresource of the resource if any
+	Subresource string `json:"subresource,omitempty"`
+
+	// Namespace is the namespace of the resource, empty for all namespaces or cluster scoped resources
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the resource, empty for all resources
+	Name string `json:"name,omitempty"`
+}
+
+// ConsolePluginIcon refers to the icon of the consoleplugin, exactly one of the fields should be set.
+// The icon should be SVG, PNG, JPEG, GIF or WebP and no larger than 64KiB, SVG icons are sanitized.
+type ConsolePluginIcon struct {
+	// SVG is the inline SVG document of the icon
+	SVG string `json:"svg,omitempty"`
+
+	// Path is the path of the icon under the base path of the backend, e.g. /icon.svg
+	Path string `json:"path,omitempty"`
+
+	// ConfigMap refers to the key of a ConfigMap holding the icon
+	ConfigMap *ConsolePluginIconConfigMap `json:"configMap,omitempty"`
+}
+
+// ConsolePluginIconConfigMap refers to the key of a ConfigMap, in either data or binaryData
+type ConsolePluginIconConfigMap struct {
+	// Name of the ConfigMap
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap
+	Namespace string `json:"namespace"`
+
+	// Key of the icon in the ConfigMap
+	Key string `json:"key"`
 }
 
 // ConsolePluginEntrypoint is an enumeration of entrypoint location
@@ -102,8 +172,40 @@ type ConsolePluginService struct {
 type ConsolePluginStatus struct {
 	// Link is the URL with which the front-end load the consoleplugin UI resource
 	Link string `json:"link"`
+
+	// Conditions represent the latest observations of the consoleplugin backend.
+	// Known condition types are [BackendResolved, BackendReachable, Ready, ManifestValid]
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LastProbeTime is the time of the last recorded probe, probes with the same result are recorded every
+	// 10 minutes at most
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// LatencyMilliseconds is the latency of the HTTP probe recorded at LastProbeTime
+	LatencyMilliseconds int64 `json:"latencyMilliseconds,omitempty"`
+
+	// LastError is the error of the last backend probe, empty if the probe succeeded
+	LastError string `json:"lastError,omitempty"`
+
+	// ManifestDigest is the sha256 digest of the last valid plugin manifest published by the backend
+	ManifestDigest string `json:"manifestDigest,omitempty"`
 }
 
+// ConsolePlugin condition types
+const (
+	// ConditionBackendResolved indicates whether the backend service of the consoleplugin exists
+	ConditionBackendResolved = "BackendResolved"
+
+	// ConditionBackendReachable indicates whether the backend answers HTTP GET on its BasePath
+	ConditionBackendReachable = "BackendReachable"
+
+	// ConditionReady indicates whether the consoleplugin is ready to be loaded by the console
+	ConditionReady = "Ready"
+
+	// ConditionManifestValid indicates whether the backend publishes a valid plugin manifest
+	ConditionManifestValid = "ManifestValid"
+)
+
 // ConsolePlugin is the Schema for the consoleplugins API
 type ConsolePlugin struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"strings"
+)
+
+// SetConsolePluginDefaults fills in the optional fields of the backend service of a ConsolePlugin
+// and normalizes its BasePath
+func SetConsolePluginDefaults(cp *ConsolePlugin) {
+	if cp.Spec.Backend == nil || cp.Spec.Backend.Service == nil {
+		return
+	}
+	svc := cp.Spec.Backend.Service
+	if svc.Port == 0 {
+		svc.Port = DefaultServicePort
+	}
+	svc.BasePath = NormalizeBasePath(svc.BasePath)
+}
+
+// NormalizeBasePath makes the base path start with a single '/', collapses repeated slashes
+// and drops the trailing slash. An empty path is normalized to DefaultBasePath.
+func NormalizeBasePath(basePath string) string {
+	var segments []string
+	for _, segment := range strings.Split(basePath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return DefaultBasePath + strings.Join(segments, "/")
+}
+
+// Default sets the defaults of the ConsolePlugin and assigns an order to it if missing.
+// The order of the old object is kept on update, otherwise the ConsolePlugin is placed after
+// all the others. It is shared by the REST API and the mutating admission webhook.
+func (cm *ConsolePluginManager) Default(cp *ConsolePlugin, old *ConsolePlugin) error {
+	SetConsolePluginDefaults(cp)
+	if cp.Spec.Order != nil {
+		return nil
+	}
+	if old != nil && old.Spec.Order != nil {
+		order := *old.Spec.Order
+		cp.Spec.Order = &order
+		return nil
+	}
+
+	consolePlugins, err := cm.listConsolePluginsForWrite()
+	if err != nil {
+		return err
+	}
+	next := int64(0)
+	for _, other := range consolePlugins {
+		if other.Name != cp.Name && other.Spec.Order != nil && *other.Spec.Order >= next {
+			next = *other.Spec.Order + 1
+		}
+	}
+	cp.Spec.Order = &next
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := []struct {
+		basePath string
+		want     string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"///", "/"},
+		{"ui", "/ui"},
+		{"/ui/", "/ui"},
+		{"//ui//page/", "/ui/page"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeBasePath(tt.basePath); got != tt.want {
+			t.Errorf("NormalizeBasePath(%q) = %q, want %q", tt.basePath, got, tt.want)
+		}
+	}
+}
+
+func TestConsolePluginManagerDefault(t *testing.T) {
+	cm := newStartedTestManager(t)
+	existingOrder := int64(7)
+	explicitOrder := int64(2)
+	tests := []struct {
+		name      string
+		order     *int64
+		old       *ConsolePlugin
+		wantOrder int64
+	}{
+		{"TestAssignOrder", nil, nil, 0},
+		{"TestKeepOrder", &explicitOrder, nil, explicitOrder},
+		{"TestKeepOldOrder", nil, &ConsolePlugin{Spec: ConsolePluginSpec{Order: &existingOrder}}, existingOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &ConsolePlugin{
+				ObjectMeta: metav1.ObjectMeta{Name: "c-plugin"},
+				Spec: ConsolePluginSpec{
+					Order: tt.order,
+					Backend: &ConsolePluginBackend{
+						Type:    ServiceBackendType,
+						Service: &ConsolePluginService{Name: "svc", Namespace: "ns", BasePath: "/ui/"},
+					},
+				},
+			}
+			if err := cm.Default(cp, tt.old); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+			if cp.Spec.Order == nil || *cp.Spec.Order != tt.wantOrder {
+				t.Errorf("Default() order = %v, want %d", cp.Spec.Order, tt.wantOrder)
+			}
+			if svc := cp.Spec.Backend.Service; svc.Port != DefaultServicePort || svc.BasePath != "/ui" {
+				t.Errorf("Default() service = %+v", svc)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+// visit states of a ConsolePlugin during a depth-first walk of the dependency graph
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+// dependencyGraph indexes ConsolePlugins by name to resolve their dependencies
+type dependencyGraph map[string]*ConsolePlugin
+
+func newDependencyGraph(consolePlugins []ConsolePlugin) dependencyGraph {
+	g := make(dependencyGraph, len(consolePlugins))
+	for i := range consolePlugins {
+		g[consolePlugins[i].Name] = &consolePlugins[i]
+	}
+	return g
+}
+
+// dependents returns the names of the ConsolePlugins depending on the given one directly
+func (g dependencyGraph) dependents(name string) []string {
+	var names []string
+	for _, cp := range g {
+		for _, dependency := range cp.Spec.Dependencies {
+			if dependency == name {
+				names = append(names, cp.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dependencyWalk walks the dependency graph depth-first from a ConsolePlugin, collecting the visited
+// ConsolePlugins in post-order and the names which are not found in the graph
+type dependencyWalk struct {
+	graph   dependencyGraph
+	next    func(cp *ConsolePlugin) []string
+	state   map[string]int
+	path    []string
+	order   []string
+	missing []string
+}
+
+func (w *dependencyWalk) visit(name string) error {
+	switch w.state[name] {
+	case visited:
+		return nil
+	case visiting:
+		for i, n := range w.path {
+			if n == name {
+				return fmt.Errorf("dependency cycle detected: %s", strings.Join(append(w.path[i:], name), " -> "))
+			}
+		}
+	}
+	cp, ok := w.graph[name]
+	if !ok {
+		w.missing = append(w.missing, name)
+		w.state[name] = visited
+		return nil
+	}
+
+	w.state[name] = visiting
+	w.path = append(w.path, name)
+	for _, n := range w.next(cp) {
+		if err := w.visit(n); err != nil {
+			return err
+		}
+	}
+	w.path = w.path[:len(w.path)-1]
+	w.state[name] = visited
+	w.order = append(w.order, name)
+	return nil
+}
+
+// dependencyOrder returns the ConsolePlugin with given name and all its transitive dependencies in
+// topological order, dependencies first. The names of the dependencies not installed are also returned.
+func (g dependencyGraph) dependencyOrder(name string) ([]string, []string, error) {
+	w := &dependencyWalk{
+		graph: g,
+		next: func(cp *ConsolePlugin) []string {
+			return cp.Spec.Dependencies
+		},
+		state: map[string]int{},
+	}
+	err := w.visit(name)
+	return w.order, w.missing, err
+}
+
+// dependentOrder returns the ConsolePlugin with given name and all the ConsolePlugins depending on it
+// transitively in topological order, dependents first
+func (g dependencyGraph) dependentOrder(name string) ([]string, error) {
+	w := &dependencyWalk{
+		graph: g,
+		next: func(cp *ConsolePlugin) []string {
+			return g.dependents(cp.Name)
+		},
+		state: map[string]int{},
+	}
+	err := w.visit(name)
+	return w.order, err
+}
+
+// SetPluginEnablement sets the enablement of the ConsolePlugin with given name and returns the names of
+// the ConsolePlugins whose enablement was changed.
+//
+// Without cascade, enabling is refused if any dependency is missing or disabled, and disabling is refused
+// if any enabled ConsolePlugin still depends on it. With cascade, the dependencies are enabled first or the
+// dependents are disabled first, in topological order. Dependency cycles are reported as conflicts, so is
+// enabling a ConsolePlugin incompatible with the console version.
+func (cm *ConsolePluginManager) SetPluginEnablement(pluginName string, enabled bool, cascade bool) ([]string, error) {
+	return cm.SetPluginEnablementIfMatch(pluginName, enabled, cascade, "")
+}
+
+// SetPluginEnablementIfMatch is SetPluginEnablement with the resourceVersion of the ConsolePlugin as
+// precondition, nothing is changed and a conflict is returned if the ConsolePlugin has been modified since.
+// The precondition is ignored if the resourceVersion is empty.
+func (cm *ConsolePluginManager) SetPluginEnablementIfMatch(pluginName string, enabled bool, cascade bool,
+	resourceVersion string) ([]string, error) {
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err != nil {
+		return nil, err
+	}
+	g := newDependencyGraph(consolePlugins)
+	cp, ok := g[pluginName]
+	if !ok {
+		return nil, apierrors.NewNotFound(consolePluginGVR.GroupResource(), pluginName)
+	}
+	if resourceVersion != "" {
+		// the cache may lag behind, so the precondition is checked against the API server before any
+		// dependency is changed, and again by the API server on the patch
+		if cp, err = GetConsolePlugin(cm.Client, pluginName); err != nil {
+			return nil, err
+		}
+		if cp.ResourceVersion != resourceVersion {
+			return nil, newResourceVersionConflict(pluginName, resourceVersion, cp.ResourceVersion)
+		}
+		g[pluginName] = cp
+	}
+
+	var order []string
+	if enabled {
+		order, err = cm.enableOrder(g, cp, cascade)
+	} else {
+		order, err = cm.disableOrder(g, cp, cascade)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		for _, name := range order {
+			if err = cm.CheckCompatibility(g[name]); err != nil {
+				return nil, newDependencyConflict(name, err)
+			}
+		}
+	}
+
+	var changed []string
+	for _, name := range order {
+		if g[name].Spec.Enabled == enabled {
+			continue
+		}
+		patch := []byte(fmt.Sprintf(`{"spec": {"enabled": %t}}`, enabled))
+		if name == pluginName && resourceVersion != "" {
+			patch = []byte(fmt.Sprintf(`{"metadata": {"resourceVersion": %q}, "spec": {"enabled": %t}}`,
+				resourceVersion, enabled))
+		}
+		if err = PatchConsolePlugin(cm.Client, name, patch); err != nil {
+			return changed, err
+		}
+		zlog.Infof("Set ConsolePlugin %s enablement to %t", name, enabled)
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+func (cm *ConsolePluginManager) enableOrder(g dependencyGraph, cp *ConsolePlugin, cascade bool) ([]string, error) {
+	if !cascade {
+		var disabled []string
+		for _, dependency := range cp.Spec.Dependencies {
+			if d, ok := g[dependency]; !ok || !d.Spec.Enabled {
+				disabled = append(disabled, dependency)
+			}
+		}
+		if len(disabled) > 0 {
+			return nil, newDependencyConflict(cp.Name,
+				fmt.Errorf("dependencies %v are missing or disabled, enable them first or cascade", disabled))
+		}
+		return []string{cp.Name}, nil
+	}
+
+	order, missing, err := g.dependencyOrder(cp.Name)
+	if err != nil {
+		return nil, newDependencyConflict(cp.Name, err)
+	}
+	if len(missing) > 0 {
+		return nil, newDependencyConflict(cp.Name, fmt.Errorf("dependencies %v are not installed", missing))
+	}
+	return order, nil
+}
+
+func (cm *ConsolePluginManager) disableOrder(g dependencyGraph, cp *ConsolePlugin, cascade bool) ([]string, error) {
+	if !cascade {
+		var enabled []string
+		for _, dependent := range g.dependents(cp.Name) {
+			if g[dependent].Spec.Enabled {
+				enabled = append(enabled, dependent)
+			}
+		}
+		if len(enabled) > 0 {
+			return nil, newDependencyConflict(cp.Name,
+				fmt.Errorf("enabled plugins %v depend on it, disable them first or cascade", enabled))
+		}
+		return []string{cp.Name}, nil
+	}
+
+	order, err := g.dependentOrder(cp.Name)
+	if err != nil {
+		return nil, newDependencyConflict(cp.Name, err)
+	}
+	return order, nil
+}
+
+func newDependencyConflict(name string, err error) *apierrors.StatusError {
+	statusErr := apierrors.NewConflict(consolePluginGVR.GroupResource(), name, err)
+	statusErr.ErrStatus.Details.Causes = []metav1.StatusCause{{
+		Type:    CauseTypeDependencyConflict,
+		Message: err.Error(),
+		Field:   "spec.dependencies",
+	}}
+	return statusErr
+}
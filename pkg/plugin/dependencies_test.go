@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// testDependencyGraph are the disabled ConsolePlugins c -> b -> a, d -> a, the cycle x <-> y and z depending on
+// a missing one
+var testDependencyGraph = []testConsolePlugin{
+	{name: "a", disabled: true},
+	{name: "b", disabled: true, dependencies: []string{"a"}},
+	{name: "c", disabled: true, dependencies: []string{"b"}},
+	{name: "d", disabled: true, dependencies: []string{"a"}},
+	{name: "x", disabled: true, dependencies: []string{"y"}},
+	{name: "y", disabled: true, dependencies: []string{"x"}},
+	{name: "z", disabled: true, dependencies: []string{"not-installed"}},
+}
+
+// withEnabled returns a copy of the ConsolePlugins with the named ones enabled
+func withEnabled(plugins []testConsolePlugin, names ...string) []testConsolePlugin {
+	enabled := append([]testConsolePlugin(nil), plugins...)
+	for i := range enabled {
+		for _, name := range names {
+			if enabled[i].name == name {
+				enabled[i].disabled = false
+			}
+		}
+	}
+	return enabled
+}
+
+func TestSetPluginEnablement(t *testing.T) {
+	tests := []struct {
+		name         string
+		manager      *ConsolePluginManager
+		pluginName   string
+		enabled      bool
+		cascade      bool
+		wantChanged  []string
+		wantConflict string
+	}{
+		{
+			"TestEnableWithDisabledDependency",
+			newTestManager(testDependencyGraph...),
+			"b", true, false,
+			nil, "[a]",
+		},
+		{
+			"TestEnableWithEnabledDependency",
+			newTestManager(withEnabled(testDependencyGraph, "a")...),
+			"b", true, false,
+			[]string{"b"}, "",
+		},
+		{
+			"TestEnableCascade",
+			newTestManager(testDependencyGraph...),
+			"c", true, true,
+			[]string{"a", "b", "c"}, "",
+		},
+		{
+			"TestEnableCascadeMissingDependency",
+			newTestManager(testDependencyGraph...),
+			"z", true, true,
+			nil, "not-installed",
+		},
+		{
+			"TestEnableCascadeCycle",
+			newTestManager(testDependencyGraph...),
+			"x", true, true,
+			nil, "x -> y -> x",
+		},
+		{
+			"TestDisableWithEnabledDependent",
+			newTestManager(withEnabled(testDependencyGraph, "a", "b")...),
+			"a", false, false,
+			nil, "[b]",
+		},
+		{
+			"TestDisableWithDisabledDependents",
+			newTestManager(withEnabled(testDependencyGraph, "a")...),
+			"a", false, false,
+			[]string{"a"}, "",
+		},
+		{
+			"TestDisableCascade",
+			newTestManager(withEnabled(testDependencyGraph, "a", "b", "c")...),
+			"a", false, true,
+			[]string{"c", "b", "a"}, "",
+		},
+		{
+			"TestUnchanged",
+			newTestManager(withEnabled(testDependencyGraph, "a", "b", "c")...),
+			"c", true, true,
+			nil, "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := tt.manager.SetPluginEnablement(tt.pluginName, tt.enabled, tt.cascade)
+			if tt.wantConflict != "" {
+				if !apierrors.IsConflict(err) || !strings.Contains(err.Error(), tt.wantConflict) {
+					t.Fatalf("SetPluginEnablement() error = %v, want conflict containing %q", err, tt.wantConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPluginEnablement() error = %v", err)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("SetPluginEnablement() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			for _, name := range changed {
+				if enabled, _ := tt.manager.CheckPluginEnablementIfInstalled(name); enabled != tt.enabled {
+					t.Errorf("ConsolePlugin %s enabled = %t, want %t", name, enabled, tt.enabled)
+				}
+			}
+		})
+	}
+
+	cm := newTestManager(testDependencyGraph...)
+	if _, err := cm.SetPluginEnablement("not-installed", true, false); !apierrors.IsNotFound(err) {
+		t.Errorf("SetPluginEnablement() error = %v, want not found", err)
+	}
+}
+
+func TestValidateDependencyCycle(t *testing.T) {
+	cm := newTestManager(testDependencyGraph...)
+	cp, err := cm.GetConsolePlugin("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Spec.Entrypoint = SideEntrypoint
+	cp.Spec.Dependencies = []string{"c"}
+	errs := cm.Validate(cp)
+	if len(errs) != 1 || errs[0].Field != "spec.dependencies" || !strings.Contains(errs[0].Detail, "a -> c -> b -> a") {
+		t.Errorf("Validate() = %v, want dependency cycle", errs)
+	}
+
+	cp.Spec.Dependencies = []string{"not-installed"}
+	if errs = cm.Validate(cp); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no error", errs)
+	}
+}
@@ -0,0 +1,382 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"plugin-management-service/pkg/constant"
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	// DefaultInstallTimeout is how long an install waits for the ConsolePlugins to become ready by default
+	DefaultInstallTimeout = 10 * time.Second
+
+	// MaxInstallTimeout bounds how long an install waits for the ConsolePlugins to become ready, well below the
+	// timeouts of the proxies in front of the service, e.g. 60s for ingress-nginx
+	MaxInstallTimeout = 30 * time.Second
+
+	// InstallRunTimeout bounds how long Helm takes to create the resources of a release, before the wait
+	InstallRunTimeout = 20 * time.Second
+
+	readyPollInterval = 2 * time.Second
+)
+
+// errNoReleaseUser is returned when a Helm operation is requested by no user to impersonate
+var errNoReleaseUser = apierrors.NewUnauthorized("no user to run the Helm operation as")
+
+// ReleaseOperation is an operation on the Helm release of a ConsolePlugin
+type ReleaseOperation string
+
+const (
+	// ReleaseUninstall uninstalls the release together with the ConsolePlugin
+	ReleaseUninstall ReleaseOperation = "Uninstall"
+
+	// ReleaseRollback rolls the release back to a previous revision, restoring the ConsolePlugin of the revision
+	ReleaseRollback ReleaseOperation = "Rollback"
+
+	// ReleaseInstall installs a chart of console extension
+	ReleaseInstall ReleaseOperation = "Install"
+)
+
+// ReleaseOperationResult is the result of an operation on the Helm release of a ConsolePlugin
+type ReleaseOperationResult struct {
+	Operation ReleaseOperation `json:"operation"`
+	DryRun    bool             `json:"dryRun"`
+
+	// Release is the uninstalled release, or the revision created by the rollback. For a dry-run rollback, it is
+	// the revision which would be rolled back to.
+	Release *ReleaseInfo `json:"release"`
+
+	// PreviousRevision is the revision of the release before the rollback
+	PreviousRevision int `json:"previousRevision,omitempty"`
+
+	// Info is the message returned by Helm, e.g. about the resources kept by the uninstall
+	Info string `json:"info,omitempty"`
+
+	// ConsolePlugins are the names of the ConsolePlugins installed by the release
+	ConsolePlugins []string `json:"consolePlugins,omitempty"`
+
+	// Ready is whether all the installed ConsolePlugins became ready before the install timed out
+	Ready bool `json:"ready,omitempty"`
+}
+
+// InstallOptions are the options to install a chart of console extension
+type InstallOptions struct {
+	// ReleaseName defaults to the chart name, Namespace to openfuyao-system
+	ReleaseName string
+	Namespace   string
+
+	// Values are the user-supplied values overriding the chart defaults
+	Values map[string]interface{}
+
+	// Timeout is how long to wait for the ConsolePlugins to become ready, DefaultInstallTimeout if 0 and
+	// MaxInstallTimeout at most
+	Timeout time.Duration
+}
+
+// ReleaseOperator runs Helm operations on the releases of ConsolePlugins, impersonating the users requesting them
+// so that Helm is allowed no more than the users are
+type ReleaseOperator struct {
+	manager *ConsolePluginManager
+
+	// newConfiguration returns the Helm action configuration for the releases in the namespace, acting as the user
+	newConfiguration func(namespace string, user *authenticationv1.UserInfo) (*action.Configuration, error)
+}
+
+// NewReleaseOperator returns a new ReleaseOperator storing the releases in Secrets like the Helm CLI
+func NewReleaseOperator(config *rest.Config, manager *ConsolePluginManager) *ReleaseOperator {
+	return &ReleaseOperator{
+		manager: manager,
+		newConfiguration: func(namespace string, user *authenticationv1.UserInfo) (*action.Configuration, error) {
+			userConfig, err := impersonatingConfig(config, user)
+			if err != nil {
+				return nil, err
+			}
+			cfg := &action.Configuration{}
+			err = cfg.Init(newRESTClientGetter(userConfig, namespace), namespace, "secret", zlog.Debugf)
+			return cfg, err
+		},
+	}
+}
+
+// impersonatingConfig returns a copy of the config of the service impersonating the user
+func impersonatingConfig(config *rest.Config, user *authenticationv1.UserInfo) (*rest.Config, error) {
+	if user == nil || user.Username == "" {
+		return nil, errNoReleaseUser
+	}
+	extra := make(map[string][]string, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = v
+	}
+	userConfig := rest.CopyConfig(config)
+	userConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: user.Username,
+		UID:      user.UID,
+		Groups:   user.Groups,
+		Extra:    extra,
+	}
+	return userConfig, nil
+}
+
+// Uninstall uninstalls the Helm release which installed the ConsolePlugin as the user. The ConsolePlugin is deleted
+// as well if the release did not delete it, e.g. when it is kept by a resource policy. Nothing is changed on dry run.
+func (o *ReleaseOperator) Uninstall(user *authenticationv1.UserInfo, cp *ConsolePlugin,
+	dryRun bool) (*ReleaseOperationResult, error) {
+	if user == nil {
+		return nil, errNoReleaseUser
+	}
+	name, namespace, err := releaseOf(cp)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := o.newConfiguration(namespace, user)
+	if err != nil {
+		return nil, err
+	}
+
+	uninstall := action.NewUninstall(cfg)
+	uninstall.DryRun = dryRun
+	uninstall.Description = fmt.Sprintf("Uninstalled with ConsolePlugin %s", cp.Name)
+	resp, err := uninstall.Run(name)
+	if err != nil {
+		return nil, releaseError(name, err)
+	}
+	result := &ReleaseOperationResult{
+		Operation: ReleaseUninstall,
+		DryRun:    dryRun,
+		Info:      resp.Info,
+	}
+	if resp.Release != nil {
+		result.Release = newReleaseInfo(resp.Release)
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err = o.manager.DeleteConsolePlugin(cp.Name); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("release %s/%s is uninstalled, but error deleting ConsolePlugin %s: %w",
+			namespace, name, cp.Name, err)
+	}
+	return result, nil
+}
+
+// Rollback rolls the Helm release which installed the ConsolePlugin back to the revision, the previous one if 0,
+// as the user. The ConsolePlugin is restored by Helm from the manifest of the revision. Nothing is changed on dry run.
+func (o *ReleaseOperator) Rollback(user *authenticationv1.UserInfo, cp *ConsolePlugin, revision int,
+	dryRun bool) (*ReleaseOperationResult, error) {
+	if user == nil {
+		return nil, errNoReleaseUser
+	}
+	if revision < 0 {
+		return nil, apierrors.NewBadRequest("revision must be non-negative")
+	}
+	name, namespace, err := releaseOf(cp)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := o.newConfiguration(namespace, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve the target the same way Helm does, so the dry run reports it and missing revisions are NotFound
+	current, err := cfg.Releases.Last(name)
+	if err != nil {
+		return nil, releaseError(name, err)
+	}
+	if revision == 0 {
+		revision = current.Version - 1
+	}
+	target, err := cfg.Releases.Get(name, revision)
+	if err != nil {
+		return nil, releaseError(name, err)
+	}
+	result := &ReleaseOperationResult{
+		Operation:        ReleaseRollback,
+		DryRun:           dryRun,
+		Release:          newReleaseInfo(target),
+		PreviousRevision: current.Version,
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.Version = revision
+	rollback.DryRun = dryRun
+	if err = rollback.Run(name); err != nil {
+		return nil, releaseError(name, err)
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	rolledBack, err := cfg.Releases.Last(name)
+	if err != nil {
+		return nil, releaseError(name, err)
+	}
+	result.Release = newReleaseInfo(rolledBack)
+	if _, err = GetConsolePlugin(o.manager.Client, cp.Name); err != nil {
+		zlog.Warnf("ConsolePlugin %s is not restored by the rollback of release %s/%s: %v",
+			cp.Name, namespace, name, err)
+	}
+	return result, nil
+}
+
+// Install installs the chart of console extension as a new release as the user, then waits for the ConsolePlugins
+// rendered by the chart to become ready. The install is not rolled back if they are not ready in time, the result
+// tells it.
+func (o *ReleaseOperator) Install(ctx context.Context, user *authenticationv1.UserInfo, ch *chart.Chart,
+	opts *InstallOptions) (*ReleaseOperationResult, error) {
+	if user == nil {
+		return nil, errNoReleaseUser
+	}
+	if err := ValidateExtensionChart(ch); err != nil {
+		return nil, err
+	}
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = ch.Metadata.Name
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = constant.PluginManagementServiceDefaultNamespace
+	}
+	for _, msg := range validation.IsDNS1123Label(opts.ReleaseName) {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid release name %q: %s", opts.ReleaseName, msg))
+	}
+	for _, msg := range validation.IsDNS1123Label(opts.Namespace) {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid namespace %q: %s", opts.Namespace, msg))
+	}
+	cfg, err := o.newConfiguration(opts.Namespace, user)
+	if err != nil {
+		return nil, err
+	}
+
+	install := action.NewInstall(cfg)
+	install.ReleaseName = opts.ReleaseName
+	install.Namespace = opts.Namespace
+	install.Description = "Installed as console extension"
+	rls, err := install.RunWithContext(ctx, ch, opts.Values)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReleaseOperationResult{
+		Operation:      ReleaseInstall,
+		Release:        newReleaseInfo(rls),
+		ConsolePlugins: consolePluginsInManifest(rls.Manifest),
+	}
+	if len(result.ConsolePlugins) == 0 {
+		zlog.Warnf("Release %s/%s installed no ConsolePlugin", opts.Namespace, opts.ReleaseName)
+		return result, nil
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultInstallTimeout
+	} else if timeout > MaxInstallTimeout {
+		timeout = MaxInstallTimeout
+	}
+	err = wait.PollUntilContextTimeout(ctx, readyPollInterval, timeout, true, func(context.Context) (bool, error) {
+		for _, name := range result.ConsolePlugins {
+			cp, err := o.manager.GetConsolePlugin(name)
+			if err != nil || !IsConsolePluginReady(cp) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	result.Ready = err == nil
+	if !result.Ready {
+		zlog.Warnf("ConsolePlugins %v of release %s/%s are not ready: %v", result.ConsolePlugins,
+			opts.Namespace, opts.ReleaseName, err)
+	}
+	return result, nil
+}
+
+// releaseOf returns the name and the namespace of the Helm release which installed the ConsolePlugin
+func releaseOf(cp *ConsolePlugin) (string, string, error) {
+	name := cp.Annotations[ReleaseNameAnnotation]
+	namespace := cp.Annotations[ReleaseNamespaceAnnotation]
+	if name == "" || namespace == "" {
+		return "", "", ErrNoRelease
+	}
+	return name, namespace, nil
+}
+
+// releaseError converts the errors of missing releases and revisions into NotFound errors
+func releaseError(name string, err error) error {
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return apierrors.NewNotFound(helmReleaseGR, name)
+	}
+	return err
+}
+
+// restClientGetter provides the Helm actions with the Kubernetes config of the service, the namespace of
+// the release being the default one
+type restClientGetter struct {
+	config    *rest.Config
+	namespace string
+}
+
+var _ genericclioptions.RESTClientGetter = &restClientGetter{}
+
+func newRESTClientGetter(config *rest.Config, namespace string) *restClientGetter {
+	return &restClientGetter{
+		config:    config,
+		namespace: namespace,
+	}
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(client), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(client)
+	return restmapper.NewShortcutExpander(mapper, client), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), &clientcmd.ConfigOverrides{
+		Context: clientcmdapi.Context{Namespace: g.namespace},
+	})
+}
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	"plugin-management-service/pkg/constant"
+)
+
+var testReleaseUser = &authenticationv1.UserInfo{Username: "admin", Groups: []string{"system:authenticated"}}
+
+// newTestReleaseOperator returns an operator managing test-plugin, installed by the release upgraded whose
+// revision 2 is deployed, with the releases stored in memory
+func newTestReleaseOperator(t *testing.T) (*ReleaseOperator, *storage.Storage) {
+	memory := driver.NewMemory()
+	memory.SetNamespace("test-ns")
+	releases := storage.Init(memory)
+	for _, rls := range []*release.Release{
+		newTestRelease("upgraded", 1, release.StatusSuperseded),
+		newTestRelease("upgraded", 2, release.StatusDeployed),
+	} {
+		if err := releases.Create(rls); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &action.Configuration{
+		Releases:     releases,
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          t.Logf,
+	}
+	cp := newTestConsolePluginUnstructured("test-plugin", testService)
+	cp.SetAnnotations(releaseAnnotations("upgraded", "test-ns"))
+	return &ReleaseOperator{
+		manager: &ConsolePluginManager{Client: newFakeDynamicClient(cp)},
+		newConfiguration: func(namespace string, user *authenticationv1.UserInfo) (*action.Configuration, error) {
+			if namespace != "test-ns" {
+				t.Errorf("configuration namespace = %s, want test-ns", namespace)
+			}
+			return cfg, nil
+		},
+	}, releases
+}
+
+func TestReleaseOperatorUninstall(t *testing.T) {
+	tests := []struct {
+		name       string
+		dryRun     bool
+		wantPlugin bool
+	}{
+		{"TestDryRun", true, true},
+		{"TestUninstall", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operator, releases := newTestReleaseOperator(t)
+			cp, err := GetConsolePlugin(operator.manager.Client, "test-plugin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := operator.Uninstall(testReleaseUser, cp, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Uninstall() error = %v", err)
+			}
+			if result.Operation != ReleaseUninstall || result.DryRun != tt.dryRun || result.Release == nil ||
+				result.Release.Revision != 2 {
+				t.Errorf("Uninstall() = %+v", result)
+			}
+			if _, err = GetConsolePlugin(operator.manager.Client, "test-plugin"); (err == nil) != tt.wantPlugin {
+				t.Errorf("ConsolePlugin present = %v, want %v", err == nil, tt.wantPlugin)
+			}
+			if _, err = releases.Last("upgraded"); (err == nil) != tt.wantPlugin {
+				t.Errorf("release present = %v, want %v", err == nil, tt.wantPlugin)
+			}
+		})
+	}
+}
+
+func TestReleaseOperatorRollback(t *testing.T) {
+	tests := []struct {
+		name         string
+		revision     int
+		dryRun       bool
+		wantRevision int
+		wantVersion  string
+		wantLast     int
+		wantErr      func(error) bool
+	}{
+		{"TestDryRun", 0, true, 1, "1.1.0", 2, nil},
+		{"TestPrevious", 0, false, 3, "1.1.0", 3, nil},
+		{"TestRevision", 1, false, 3, "1.1.0", 3, nil},
+		{"TestMissingRevision", 5, false, 0, "", 2, apierrors.IsNotFound},
+		{"TestNegativeRevision", -1, false, 0, "", 2, apierrors.IsBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operator, releases := newTestReleaseOperator(t)
+			cp, err := GetConsolePlugin(operator.manager.Client, "test-plugin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := operator.Rollback(testReleaseUser, cp, tt.revision, tt.dryRun)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("Rollback() error = %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Rollback() error = %v", err)
+			} else if result.Release.Revision != tt.wantRevision || result.Release.ChartVersion != tt.wantVersion ||
+				result.PreviousRevision != 2 {
+				t.Errorf("Rollback() = %+v, release %+v", result, result.Release)
+			}
+			last, err := releases.Last("upgraded")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last.Version != tt.wantLast {
+				t.Errorf("last revision = %d, want %d", last.Version, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestReleaseOperatorNoRelease(t *testing.T) {
+	operator, _ := newTestReleaseOperator(t)
+	cp := &ConsolePlugin{}
+	cp.Name = "manual-plugin"
+	if _, err := operator.Uninstall(testReleaseUser, cp, true); !errors.Is(err, ErrNoRelease) {
+		t.Errorf("Uninstall() error = %v, want %v", err, ErrNoRelease)
+	}
+	if _, err := operator.Rollback(testReleaseUser, cp, 0, true); !errors.Is(err, ErrNoRelease) {
+		t.Errorf("Rollback() error = %v, want %v", err, ErrNoRelease)
+	}
+}
+
+// newTestExtensionChart returns a chart rendering the ConsolePlugin named by the value pluginName
+func newTestExtensionChart(keywords ...string) *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "extension",
+			Version:    "0.1.0",
+			Keywords:   keywords,
+		},
+		Templates: []*chart.File{{
+			Name: "templates/consoleplugin.yaml",
+			Data: []byte(`apiVersion: console.openfuyao.com/v1beta1
+kind: ConsolePlugin
+metadata:
+  name: {{ .Values.pluginName }}
+spec:
+  pluginName: {{ .Values.pluginName }}
+  displayName: Extension
+  entrypoint: Side
+`),
+		}},
+		Values: map[string]interface{}{"pluginName": "extension-plugin"},
+	}
+}
+
+func TestReleaseOperatorInstall(t *testing.T) {
+	tests := []struct {
+		name        string
+		chart       *chart.Chart
+		opts        *InstallOptions
+		wantPlugins []string
+		wantReady   bool
+		wantErr     func(error) bool
+	}{
+		{"TestReady", newTestExtensionChart(constant.FuyaoExtensionKeyword), &InstallOptions{
+			ReleaseName: "installed", Namespace: "test-ns", Values: map[string]interface{}{"pluginName": "test-plugin"},
+		}, []string{"test-plugin"}, true, nil},
+		{"TestNotReady", newTestExtensionChart(constant.FuyaoExtensionKeyword), &InstallOptions{
+			Namespace: "test-ns", Timeout: 10 * time.Millisecond,
+		}, []string{"extension-plugin"}, false, nil},
+		{"TestNotExtension", newTestExtensionChart("other"), &InstallOptions{Namespace: "test-ns"},
+			nil, false, apierrors.IsBadRequest},
+		{"TestInvalidReleaseName", newTestExtensionChart(constant.FuyaoExtensionKeyword),
+			&InstallOptions{ReleaseName: "Invalid_Name", Namespace: "test-ns"}, nil, false, apierrors.IsBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operator, releases := newTestReleaseOperator(t)
+			setTestConsolePluginReady(t, operator.manager.Client, "test-plugin")
+			result, err := operator.Install(context.Background(), testReleaseUser, tt.chart, tt.opts)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("Install() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+			if result.Operation != ReleaseInstall || result.Ready != tt.wantReady ||
+				!reflect.DeepEqual(result.ConsolePlugins, tt.wantPlugins) {
+				t.Errorf("Install() = %+v", result)
+			}
+			if _, err = releases.Deployed(tt.opts.ReleaseName); err != nil {
+				t.Errorf("release %s not deployed: %v", tt.opts.ReleaseName, err)
+			}
+		})
+	}
+}
+
+func setTestConsolePluginReady(t *testing.T, client dynamic.Interface, name string) {
+	resource := client.Resource(consolePluginGVR)
+	obj, err := resource.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conditions := []interface{}{map[string]interface{}{
+		"type": ConditionReady, "status": string(metav1.ConditionTrue), "reason": "Ready",
+	}}
+	if err = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = resource.Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleaseOperatorRequiresUser(t *testing.T) {
+	operator, _ := newTestReleaseOperator(t)
+	cp, err := operator.manager.GetConsolePlugin("test-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = operator.Uninstall(nil, cp, true); !apierrors.IsUnauthorized(err) {
+		t.Errorf("Uninstall() error = %v, want unauthorized", err)
+	}
+	if _, err = operator.Rollback(nil, cp, 0, true); !apierrors.IsUnauthorized(err) {
+		t.Errorf("Rollback() error = %v, want unauthorized", err)
+	}
+	_, err = operator.Install(context.Background(), nil, &chart.Chart{}, &InstallOptions{})
+	if !apierrors.IsUnauthorized(err) {
+		t.Errorf("Install() error = %v, want unauthorized", err)
+	}
+}
+
+func TestImpersonatingConfig(t *testing.T) {
+	config := &rest.Config{Host: "https://kubernetes.default", BearerToken: "service-token"}
+	user := &authenticationv1.UserInfo{
+		Username: "admin",
+		UID:      "42",
+		Groups:   []string{"system:authenticated"},
+		Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"console"}},
+	}
+	got, err := impersonatingConfig(config, user)
+	if err != nil {
+		t.Fatalf("impersonatingConfig() error = %v", err)
+	}
+	want := rest.ImpersonationConfig{
+		UserName: "admin",
+		UID:      "42",
+		Groups:   []string{"system:authenticated"},
+		Extra:    map[string][]string{"scopes": {"console"}},
+	}
+	if !reflect.DeepEqual(got.Impersonate, want) || got.BearerToken != "service-token" {
+		t.Errorf("impersonatingConfig() = %+v, want %+v with the service token", got.Impersonate, want)
+	}
+	if config.Impersonate.UserName != "" {
+		t.Errorf("impersonatingConfig() changed the config of the service")
+	}
+	if _, err = impersonatingConfig(config, &authenticationv1.UserInfo{}); !apierrors.IsUnauthorized(err) {
+		t.Errorf("impersonatingConfig() error = %v, want unauthorized for no user", err)
+	}
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MaxIconBytes is the maximum size of an icon
+	MaxIconBytes = 64 << 10
+
+	// MIMETypeSVG is the MIME type of SVG icons
+	MIMETypeSVG = "image/svg+xml"
+
+	maxIconPathLength = 256
+)
+
+var (
+	// ErrNoIcon is returned when the ConsolePlugin has no icon
+	ErrNoIcon = errors.New("consoleplugin has no icon")
+
+	// ErrInvalidIcon is returned when the icon is too large, of unsupported MIME type or malformed
+	ErrInvalidIcon = errors.New("invalid icon")
+
+	// supportedIconTypes are the MIME types of the raster icons detected from their content
+	supportedIconTypes = map[string]struct{}{
+		"image/png":  {},
+		"image/jpeg": {},
+		"image/gif":  {},
+		"image/webp": {},
+	}
+
+	// safeSVGElements are the shape, gradient, text and structural elements kept in the SVG icons, the others
+	// are removed along with their content, e.g. scripts, styles, animations and foreign objects
+	safeSVGElements = toSet("svg", "g", "defs", "symbol", "use", "title", "desc", "path", "rect", "circle",
+		"ellipse", "line", "polyline", "polygon", "text", "tspan", "textpath", "lineargradient", "radialgradient",
+		"stop", "clippath", "mask", "pattern", "marker")
+
+	// safeSVGAttrs are the geometry and presentation attributes kept in the SVG icons, in lower case
+	safeSVGAttrs = toSet("id", "class", "version", "viewbox", "preserveaspectratio", "width", "height",
+		"x", "y", "x1", "y1", "x2", "y2", "cx", "cy", "r", "rx", "ry", "fx", "fy", "fr", "dx", "dy", "d", "points",
+		"pathlength", "transform", "fill", "fill-opacity", "fill-rule", "stroke", "stroke-width", "stroke-opacity",
+		"stroke-linecap", "stroke-linejoin", "stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset",
+		"opacity", "color", "display", "visibility", "clip-path", "clip-rule", "clippathunits", "mask",
+		"maskunits", "maskcontentunits", "offset", "stop-color", "stop-opacity", "gradientunits",
+		"gradienttransform", "spreadmethod", "patternunits", "patterncontentunits", "patterntransform",
+		"markerwidth", "markerheight", "markerunits", "refx", "refy", "orient", "marker-start", "marker-mid",
+		"marker-end", "font-family", "font-size", "font-style", "font-weight", "text-anchor",
+		"dominant-baseline", "letter-spacing", "textlength", "lengthadjust", "startoffset", "vector-effect",
+		"shape-rendering", "href")
+
+	// unsafeSVGValues are the schemes and CSS functions rejected in the attribute values once normalized
+	unsafeSVGValues = []string{"javascript:", "vbscript:", "data:", "expression("}
+)
+
+// Icon is a validated icon ready to be served
+type Icon struct {
+	// ContentType is the MIME type detected from the content
+	ContentType string
+
+	// Data is the content of the icon, SVG icons are sanitized
+	Data []byte
+
+	// Digest is the sha256 digest of Data
+	Digest string
+}
+
+// NewIcon validates the size and the MIME type of the icon content, and sanitizes SVG icons
+func NewIcon(data []byte) (*Icon, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty content", ErrInvalidIcon)
+	}
+	if len(data) > MaxIconBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidIcon, MaxIconBytes)
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := supportedIconTypes[contentType]; !ok {
+		// SVG is text and is only detected as XML or plain text
+		sanitized, err := SanitizeSVG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unsupported content type %s", ErrInvalidIcon, contentType)
+		}
+		data, contentType = sanitized, MIMETypeSVG
+	}
+	sum := sha256.Sum256(data)
+	return &Icon{
+		ContentType: contentType,
+		Data:        data,
+		Digest:      "sha256:" + hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// SanitizeSVG returns the SVG document with only the allowed elements and attributes, and without external
+// references, comments, processing instructions and DOCTYPE. An error is returned if the document is malformed
+// or its root element is not svg.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	var out bytes.Buffer
+	depth, skipDepth, roots := 0, 0, 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed SVG: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				roots++
+				if !strings.EqualFold(t.Name.Local, "svg") || roots > 1 {
+					return nil, errors.New("root element must be a single svg")
+				}
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if _, ok := safeSVGElements[strings.ToLower(t.Name.Local)]; !ok || t.Name.Space != "" {
+				skipDepth = depth
+				continue
+			}
+			writeSVGStartElement(&out, t)
+		case xml.EndElement:
+			depth--
+			if skipDepth > 0 {
+				if depth < skipDepth {
+					skipDepth = 0
+				}
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth == 0 && depth > 0 {
+				_ = xml.EscapeText(&out, t)
+			}
+		}
+	}
+	if roots == 0 || depth != 0 {
+		return nil, errors.New("root element must be a single svg")
+	}
+	return out.Bytes(), nil
+}
+
+func writeSVGStartElement(out *bytes.Buffer, t xml.StartElement) {
+	out.WriteString("<" + qualifiedName(t.Name))
+	for _, attr := range t.Attr {
+		if !isSafeSVGAttr(attr) {
+			continue
+		}
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		_ = xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+// isSafeSVGAttr keeps the namespace declarations and the allowed attributes whose values only refer to the
+// document itself
+func isSafeSVGAttr(attr xml.Attr) bool {
+	if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+		return true
+	}
+	name := strings.ToLower(attr.Name.Local)
+	if _, ok := safeSVGAttrs[name]; !ok {
+		return false
+	}
+	if attr.Name.Space != "" && !(attr.Name.Space == "xlink" && name == "href") {
+		return false
+	}
+	value := normalizeSVGValue(attr.Value)
+	if name == "href" {
+		return strings.HasPrefix(value, "#")
+	}
+	for _, unsafe := range unsafeSVGValues {
+		if strings.Contains(value, unsafe) {
+			return false
+		}
+	}
+	for rest := value; ; {
+		i := strings.Index(rest, "url(")
+		if i < 0 {
+			return true
+		}
+		rest = strings.TrimLeft(rest[i+len("url("):], `"'`)
+		if !strings.HasPrefix(rest, "#") {
+			return false
+		}
+	}
+}
+
+// normalizeSVGValue lowercases the value and removes the whitespaces and control characters, which browsers
+// ignore in URL schemes
+func normalizeSVGValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, value)
+}
+
+func toSet(values ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// IconFetcher gets the icons of ConsolePlugins from where their specs refer to
+type IconFetcher struct {
+	kubeClient kubernetes.Interface
+	httpClient *http.Client
+}
+
+// NewIconFetcher returns a new IconFetcher reading ConfigMaps with the given client
+func NewIconFetcher(kubeClient kubernetes.Interface) *IconFetcher {
+	return &IconFetcher{
+		kubeClient: kubeClient,
+		httpClient: &http.Client{
+			Timeout: defaultProbeTimeout,
+		},
+	}
+}
+
+// Fetch gets the icon of the ConsolePlugin and validates it. The link is the base URL of the backend,
+// used if the icon is a path on the backend. ErrNoIcon is returned if the ConsolePlugin has no icon,
+// an error wrapping ErrInvalidIcon if the icon could be fetched but is invalid.
+func (f *IconFetcher) Fetch(ctx context.Context, cp *ConsolePlugin, link string) (*Icon, error) {
+	icon := cp.Spec.Icon
+	switch {
+	case icon == nil:
+		return nil, ErrNoIcon
+	case icon.SVG != "":
+		return NewIcon([]byte(icon.SVG))
+	case icon.Path != "":
+		return f.fetchFromBackend(ctx, cp, link, icon.Path)
+	case icon.ConfigMap != nil:
+		return f.fetchFromConfigMap(ctx, icon.ConfigMap)
+	}
+	return nil, ErrNoIcon
+}
+
+func (f *IconFetcher) fetchFromBackend(ctx context.Context, cp *ConsolePlugin, link, iconPath string) (*Icon, error) {
+	if link == "" {
+		return nil, fmt.Errorf("consoleplugin %s has no service backend", cp.Name)
+	}
+	iconURL := strings.TrimSuffix(link, "/") + path.Clean(iconPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP GET %s returned status code %d", iconURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxIconBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	return NewIcon(data)
+}
+
+func (f *IconFetcher) fetchFromConfigMap(ctx context.Context, ref *ConsolePluginIconConfigMap) (*Icon, error) {
+	if f.kubeClient == nil {
+		return nil, errors.New("no kubernetes client to read ConfigMaps")
+	}
+	cm, err := f.kubeClient.CoreV1().ConfigMaps(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := cm.BinaryData[ref.Key]; ok {
+		return NewIcon(data)
+	}
+	if data, ok := cm.Data[ref.Key]; ok {
+		return NewIcon([]byte(data))
+	}
+	return nil, fmt.Errorf("key %s not found in ConfigMap %s/%s", ref.Key, ref.Namespace, ref.Name)
+}
+
+func validateIcon(icon *ConsolePluginIcon, fldPath *field.Path) field.ErrorList {
+	if icon == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	sources := 0
+	if icon.SVG != "" {
+		sources++
+		svgPath := fldPath.Child("svg")
+		if len(icon.SVG) > MaxIconBytes {
+			allErrs = append(allErrs, field.TooLong(svgPath, "", MaxIconBytes))
+		} else if _, err := SanitizeSVG([]byte(icon.SVG)); err != nil {
+			allErrs = append(allErrs, field.Invalid(svgPath, "", err.Error()))
+		}
+	}
+	if icon.Path != "" {
+		sources++
+		allErrs = append(allErrs, validateIconPath(icon.Path, fldPath.Child("path"))...)
+	}
+	if icon.ConfigMap != nil {
+		sources++
+		cmPath := fldPath.Child("configMap")
+		allErrs = append(allErrs, validateName(icon.ConfigMap.Name, cmPath.Child("name"))...)
+		allErrs = append(allErrs, validateName(icon.ConfigMap.Namespace, cmPath.Child("namespace"))...)
+		for _, msg := range validation.IsConfigMapKey(icon.ConfigMap.Key) {
+			allErrs = append(allErrs, field.Invalid(cmPath.Child("key"), icon.ConfigMap.Key, msg))
+		}
+	}
+	if sources != 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "exactly one of svg, path and configMap is required"))
+	}
+	return allErrs
+}
+
+func validateIconPath(iconPath string, fldPath *field.Path) field.ErrorList {
+	if len(iconPath) > maxIconPathLength {
+		return field.ErrorList{field.TooLong(fldPath, iconPath, maxIconPathLength)}
+	}
+	if !strings.HasPrefix(iconPath, "/") || strings.Contains(iconPath, "?") || strings.Contains(iconPath, "#") {
+		return field.ErrorList{field.Invalid(fldPath, iconPath, "must be an absolute path under the base path")}
+	}
+	for _, segment := range strings.Split(iconPath, "/") {
+		if segment == ".." {
+			return field.ErrorList{field.Invalid(fldPath, iconPath, "must not contain '..'")}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16"><circle cx="8" cy="8" r="8"/></svg>`
+
+// testPNG is the signature and the beginning of the header chunk of a PNG image
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestSanitizeSVG(t *testing.T) {
+	tests := []struct {
+		name    string
+		svg     string
+		want    string
+		wantErr bool
+	}{
+		{
+			"TestClean",
+			testSVG,
+			`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16"><circle cx="8" cy="8" r="8"></circle></svg>`,
+			false,
+		},
+		{
+			"TestScript",
+			`<svg><script>alert(1)</script><g><script type="text/javascript"><![CDATA[alert(2)]]></script></g></svg>`,
+			`<svg><g></g></svg>`,
+			false,
+		},
+		{
+			"TestEventHandlers",
+			`<svg onload="alert(1)"><rect width="1" OnClick="alert(2)"/></svg>`,
+			`<svg><rect width="1"></rect></svg>`,
+			false,
+		},
+		{
+			"TestExternalReferences",
+			`<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="http://evil/x.svg#a"/>` +
+				`<use href="#b"/><a href="javascript:alert(1)"/><rect style="fill:url(http://evil/x)"/></svg>`,
+			`<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use></use><use href="#b"></use><rect></rect></svg>`,
+			false,
+		},
+		{
+			"TestLocalReferences",
+			`<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/><rect fill="url('#g')"/>` +
+				`<rect fill="url(#g) url(http://evil/x)"/></svg>`,
+			`<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"></use>` +
+				`<rect fill="url(&#39;#g&#39;)"></rect><rect></rect></svg>`,
+			false,
+		},
+		{
+			"TestAnimations",
+			`<svg><a><animate attributeName="href" to="java&#9;script:alert(1)"/><text>x</text></a>` +
+				`<rect><set attributeName="fill" to="red"/><animateMotion path="M0 0"/></rect></svg>`,
+			`<svg><rect></rect></svg>`,
+			false,
+		},
+		{
+			"TestStyle",
+			`<svg><style>@import url(http://evil/x.css);</style><rect style="fill:red" width="1"/></svg>`,
+			`<svg><rect width="1"></rect></svg>`,
+			false,
+		},
+		{
+			"TestObfuscatedScheme",
+			`<svg><use href=" JAVA&#x0A;script:alert(1)"/><rect fill="java&#x7F;script:x" stroke="red"/></svg>`,
+			`<svg><use></use><rect stroke="red"></rect></svg>`,
+			false,
+		},
+		{
+			"TestUnknownAttributes",
+			`<svg><rect width="1" data-x="y" xml:space="preserve" attributeName="href"/></svg>`,
+			`<svg><rect width="1"></rect></svg>`,
+			false,
+		},
+		{
+			"TestForeignObject",
+			`<svg><foreignObject><iframe src="http://evil"/></foreignObject><path d="M0 0"/></svg>`,
+			`<svg><path d="M0 0"></path></svg>`,
+			false,
+		},
+		{
+			"TestDoctypeAndComments",
+			`<?xml version="1.0"?><!DOCTYPE svg [<!ENTITY x "y">]><!-- c --><svg><title>a &lt; b</title></svg>`,
+			`<svg><title>a &lt; b</title></svg>`,
+			false,
+		},
+		{"TestNotSVG", `<html><body/></html>`, "", true},
+		{"TestMultipleRoots", `<svg/><svg/>`, "", true},
+		{"TestMalformed", `<svg><g></svg>`, "", true},
+		{"TestEmpty", ``, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeSVG([]byte(tt.svg))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SanitizeSVG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("SanitizeSVG() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIcon(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+	}{
+		{"TestSVG", []byte(testSVG), MIMETypeSVG},
+		{"TestPNG", testPNG, "image/png"},
+		{"TestHTML", []byte(`<html><script>alert(1)</script></html>`), ""},
+		{"TestText", []byte("not an icon"), ""},
+		{"TestTooLarge", append(append([]byte{}, testPNG...), make([]byte, MaxIconBytes)...), ""},
+		{"TestEmpty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icon, err := NewIcon(tt.data)
+			if tt.wantContentType == "" {
+				if !errors.Is(err, ErrInvalidIcon) {
+					t.Errorf("NewIcon() error = %v, want ErrInvalidIcon", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewIcon() error = %v", err)
+			}
+			if icon.ContentType != tt.wantContentType || !strings.HasPrefix(icon.Digest, "sha256:") {
+				t.Errorf("NewIcon() = %s %s, want %s", icon.ContentType, icon.Digest, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestIconFetcherFetch(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ui/icon.png":
+			_, _ = w.Write(testPNG)
+		case "/ui/evil.svg":
+			_, _ = w.Write([]byte(`<svg><script>alert(1)</script></svg>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer backend.Close()
+	fetcher := NewIconFetcher(kubefake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "icons", Namespace: "ns"},
+		Data:       map[string]string{"icon.svg": testSVG},
+		BinaryData: map[string][]byte{"icon.png": testPNG},
+	}))
+
+	tests := []struct {
+		name     string
+		icon     *ConsolePluginIcon
+		wantData []byte
+		wantErr  error
+	}{
+		{"TestNoIcon", nil, nil, ErrNoIcon},
+		{"TestInline", &ConsolePluginIcon{SVG: testSVG}, nil, nil},
+		{"TestBackendPath", &ConsolePluginIcon{Path: "/icon.png"}, testPNG, nil},
+		{"TestBackendSanitized", &ConsolePluginIcon{Path: "/evil.svg"}, []byte("<svg></svg>"), nil},
+		{"TestBackendNotFound", &ConsolePluginIcon{Path: "/missing.svg"}, nil, errors.New("status code 404")},
+		{"TestConfigMapData", &ConsolePluginIcon{
+			ConfigMap: &ConsolePluginIconConfigMap{Name: "icons", Namespace: "ns", Key: "icon.svg"}}, nil, nil},
+		{"TestConfigMapBinaryData", &ConsolePluginIcon{
+			ConfigMap: &ConsolePluginIconConfigMap{Name: "icons", Namespace: "ns", Key: "icon.png"}}, testPNG, nil},
+		{"TestConfigMapMissingKey", &ConsolePluginIcon{
+			ConfigMap: &ConsolePluginIconConfigMap{Name: "icons", Namespace: "ns", Key: "x"}}, nil,
+			errors.New("key x not found")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &ConsolePlugin{Spec: ConsolePluginSpec{Icon: tt.icon}}
+			icon, err := fetcher.Fetch(context.Background(), cp, backend.URL+"/ui/")
+			if tt.wantErr != nil {
+				if err == nil || !(errors.Is(err, tt.wantErr) || strings.Contains(err.Error(), tt.wantErr.Error())) {
+					t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if tt.wantData != nil && !bytes.Equal(icon.Data, tt.wantData) {
+				t.Errorf("Fetch() = %q, want %q", icon.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestValidateIcon(t *testing.T) {
+	tests := []struct {
+		name       string
+		icon       *ConsolePluginIcon
+		wantFields []string
+	}{
+		{"TestNil", nil, nil},
+		{"TestInline", &ConsolePluginIcon{SVG: testSVG}, nil},
+		{"TestInvalidSVG", &ConsolePluginIcon{SVG: "<html/>"}, []string{"spec.icon.svg"}},
+		{"TestSVGTooLarge", &ConsolePluginIcon{SVG: strings.Repeat(" ", MaxIconBytes+1)}, []string{"spec.icon.svg"}},
+		{"TestPath", &ConsolePluginIcon{Path: "/assets/icon.svg"}, nil},
+		{"TestRelativePath", &ConsolePluginIcon{Path: "icon.svg"}, []string{"spec.icon.path"}},
+		{"TestPathTraversal", &ConsolePluginIcon{Path: "/../admin"}, []string{"spec.icon.path"}},
+		{"TestConfigMap", &ConsolePluginIcon{
+			ConfigMap: &ConsolePluginIconConfigMap{Name: "icons", Namespace: "ns", Key: "icon.svg"}}, nil},
+		{"TestInvalidConfigMapKey", &ConsolePluginIcon{
+			ConfigMap: &ConsolePluginIconConfigMap{Name: "icons", Namespace: "ns", Key: "a/b"}},
+			[]string{"spec.icon.configMap.key"}},
+		{"TestNoSource", &ConsolePluginIcon{}, []string{"spec.icon"}},
+		{"TestMultipleSources", &ConsolePluginIcon{SVG: testSVG, Path: "/icon.svg"}, []string{"spec.icon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateIcon(tt.icon, field.NewPath("spec", "icon"))
+			if got := errorFields(errs); strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("validateIcon() fields = %v, want %v (%v)", got, tt.wantFields, errs)
+			}
+		})
+	}
+}
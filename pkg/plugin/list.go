@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ReleaseNameAnnotation is the annotation of the Helm release which installed the ConsolePlugin
+const ReleaseNameAnnotation = "meta.helm.sh/release-name"
+
+// sort keys of ConsolePlugin lists
+const (
+	SortByName        = "name"
+	SortByOrder       = "order"
+	SortByDisplayName = "displayName"
+)
+
+// ListOptions filters, sorts and paginates a list of ConsolePlugins
+type ListOptions struct {
+	// LabelSelector selects the ConsolePlugins by labels, everything is selected if nil
+	LabelSelector labels.Selector
+
+	// Entrypoint selects the ConsolePlugins with the entrypoint if not empty
+	Entrypoint ConsolePluginEntrypoint
+
+	// Enabled selects the ConsolePlugins with the enablement if not nil. A ConsolePlugin incompatible with the
+	// console version is disabled, whatever its spec.
+	Enabled *bool
+
+	// Release selects the ConsolePlugins installed by the Helm release if not empty
+	Release string
+
+	// Search selects the ConsolePlugins whose name or display name contains it, case-insensitively
+	Search string
+
+	// SortBy is one of [name, order, displayName], default to be name
+	SortBy string
+
+	// Languages are the languages preferred by the client, the display names are localized for them before
+	// being searched or sorted
+	Languages []language.Tag
+
+	// Visible returns the ConsolePlugin as visible to the client, false if it is hidden. The hidden
+	// ConsolePlugins are filtered out before pagination. Every ConsolePlugin is visible if nil.
+	Visible func(cp *ConsolePlugin) (*ConsolePlugin, bool)
+
+	// Limit is the maximum number of ConsolePlugins returned, no limit if zero
+	Limit int64
+
+	// Continue is the token returned by the previous page
+	Continue string
+}
+
+// ConsolePluginPage is a page of the ConsolePlugin list
+type ConsolePluginPage struct {
+	Items []ConsolePlugin
+
+	// Continue is the token to get the next page, empty on the last page
+	Continue string
+
+	// RemainingItemCount is the number of ConsolePlugins after this page if known
+	RemainingItemCount *int64
+}
+
+// pageToken is the continue token of a page listed from the cache
+type pageToken struct {
+	Offset int `json:"offset"`
+}
+
+func (o *ListOptions) validate() error {
+	switch o.SortBy {
+	case "", SortByName, SortByOrder, SortByDisplayName:
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf("unsupported sortBy %q, supported values: %s, %s, %s",
+			o.SortBy, SortByName, SortByOrder, SortByDisplayName))
+	}
+	if o.Limit < 0 {
+		return apierrors.NewBadRequest("limit must be non-negative")
+	}
+	return nil
+}
+
+// filtered returns whether filters other than the label selector or sorting other than by name are set,
+// which the API server could not serve
+func (o *ListOptions) filtered() bool {
+	return o.Entrypoint != "" || o.Enabled != nil || o.Release != "" || o.Search != "" || o.Visible != nil ||
+		(o.SortBy != "" && o.SortBy != SortByName)
+}
+
+// displayName returns the display name of the ConsolePlugin localized for the preferred languages
+func (o *ListOptions) displayName(cp *ConsolePlugin) string {
+	return LocalizeDisplayName(cp.Spec.DisplayName, cp.Spec.DisplayNames, o.Languages)
+}
+
+// Matches returns whether the ConsolePlugin is selected by the filters of the options except the label selector
+// and the visibility, enabled being the effective enablement of the ConsolePlugin
+func (o *ListOptions) Matches(cp *ConsolePlugin, enabled bool) bool {
+	if o.Entrypoint != "" && cp.Spec.Entrypoint != o.Entrypoint {
+		return false
+	}
+	if o.Enabled != nil && enabled != *o.Enabled {
+		return false
+	}
+	if o.Release != "" && cp.Annotations[ReleaseNameAnnotation] != o.Release {
+		return false
+	}
+	if o.Search != "" {
+		search := strings.ToLower(o.Search)
+		if !strings.Contains(strings.ToLower(cp.Name), search) &&
+			!strings.Contains(strings.ToLower(o.displayName(cp)), search) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *ListOptions) sort(consolePlugins []ConsolePlugin) {
+	switch o.SortBy {
+	case SortByOrder:
+		SortConsolePluginsByOrder(consolePlugins)
+	case SortByDisplayName:
+		displayNames := make(map[string]string, len(consolePlugins))
+		for i := range consolePlugins {
+			displayNames[consolePlugins[i].Name] = o.displayName(&consolePlugins[i])
+		}
+		sort.SliceStable(consolePlugins, func(i, j int) bool {
+			di, dj := displayNames[consolePlugins[i].Name], displayNames[consolePlugins[j].Name]
+			if di != dj {
+				return di < dj
+			}
+			return consolePlugins[i].Name < consolePlugins[j].Name
+		})
+	default:
+		sort.SliceStable(consolePlugins, func(i, j int) bool {
+			return consolePlugins[i].Name < consolePlugins[j].Name
+		})
+	}
+}
+
+// ListConsolePluginsPage returns a page of the ConsolePlugins filtered and sorted by the options.
+// Pages are cut from the cache once the manager is started, after the hidden ConsolePlugins are filtered out.
+// Without cache, the label selector, limit and continue token are passed through to the API server unless other
+// filters or sorting are requested.
+func (cm *ConsolePluginManager) ListConsolePluginsPage(opts *ListOptions) (*ConsolePluginPage, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	selector := opts.LabelSelector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	if cm.lister == nil && !opts.filtered() {
+		return cm.listConsolePluginsPageFromServer(selector, opts)
+	}
+
+	offset := 0
+	if opts.Continue != "" {
+		token, err := decodePageToken(opts.Continue)
+		if err != nil {
+			return nil, err
+		}
+		offset = token.Offset
+	}
+
+	consolePlugins, err := cm.ListConsolePluginsBySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]ConsolePlugin, 0, len(consolePlugins))
+	for i := range consolePlugins {
+		cp := &consolePlugins[i]
+		if !opts.Matches(cp, cp.Spec.Enabled && cm.CheckCompatibility(cp) == nil) {
+			continue
+		}
+		if opts.Visible != nil {
+			var visible bool
+			if cp, visible = opts.Visible(cp); !visible {
+				continue
+			}
+		}
+		matched = append(matched, *cp)
+	}
+	opts.sort(matched)
+
+	page := &ConsolePluginPage{}
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	end := len(matched)
+	if opts.Limit > 0 && int64(end-offset) > opts.Limit {
+		end = offset + int(opts.Limit)
+		page.Continue = encodePageToken(&pageToken{Offset: end})
+		remaining := int64(len(matched) - end)
+		page.RemainingItemCount = &remaining
+	}
+	page.Items = matched[offset:end]
+	return page, nil
+}
+
+func (cm *ConsolePluginManager) listConsolePluginsPageFromServer(selector labels.Selector,
+	opts *ListOptions) (*ConsolePluginPage, error) {
+	cpList, err := cm.Client.Resource(consolePluginGVR).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+		Limit:         opts.Limit,
+		Continue:      opts.Continue,
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := &ConsolePluginPage{
+		Items:              make([]ConsolePlugin, 0, len(cpList.Items)),
+		Continue:           cpList.GetContinue(),
+		RemainingItemCount: cpList.GetRemainingItemCount(),
+	}
+	for _, item := range cpList.Items {
+		var cp ConsolePlugin
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &cp); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, cp)
+	}
+	return page, nil
+}
+
+func encodePageToken(token *pageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(s string) (*pageToken, error) {
+	token := &pageToken{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, token)
+	}
+	if err != nil || token.Offset < 0 {
+		return nil, apierrors.NewBadRequest("invalid continue token")
+	}
+	return token, nil
+}
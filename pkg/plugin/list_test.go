@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// testListedPlugins are the ConsolePlugins listed by the tests, labeled with their entrypoint as tier
+var testListedPlugins = []testConsolePlugin{
+	{name: "alpha", displayName: "Monitoring", entrypoint: "Side", order: testOrder(2), release: "monitoring",
+		labels: map[string]string{"tier": "Side"}},
+	{name: "beta", displayName: "Logging", entrypoint: "Side", order: testOrder(0), disabled: true,
+		release: "logging", labels: map[string]string{"tier": "Side"}},
+	{name: "gamma", displayName: "Alerting", entrypoint: "Nav", order: testOrder(1), release: "monitoring",
+		labels: map[string]string{"tier": "Nav"}},
+	{name: "delta", displayName: "Dashboard", entrypoint: "Nav", labels: map[string]string{"tier": "Nav"}},
+}
+
+func pageNames(page *ConsolePluginPage) string {
+	var names []string
+	for _, cp := range page.Items {
+		names = append(names, cp.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestListConsolePluginsPage(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name string
+		opts ListOptions
+		want string
+	}{
+		{"TestDefault", ListOptions{}, "alpha,beta,delta,gamma"},
+		{"TestEntrypoint", ListOptions{Entrypoint: NavEntrypoint}, "delta,gamma"},
+		{"TestEnabled", ListOptions{Enabled: &enabled}, "alpha,delta,gamma"},
+		{"TestDisabled", ListOptions{Enabled: &disabled}, "beta"},
+		{"TestRelease", ListOptions{Release: "monitoring"}, "alpha,gamma"},
+		{"TestLabelSelector", ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{"tier": "Side"})},
+			"alpha,beta"},
+		{"TestSearchName", ListOptions{Search: "TA"}, "beta,delta"},
+		{"TestSearchDisplayName", ListOptions{Search: "ing"}, "alpha,beta,gamma"},
+		{"TestSortByOrder", ListOptions{SortBy: SortByOrder}, "beta,gamma,alpha,delta"},
+		{"TestSortByDisplayName", ListOptions{SortBy: SortByDisplayName}, "gamma,delta,beta,alpha"},
+		{"TestCombined", ListOptions{Enabled: &enabled, Search: "a", SortBy: SortByOrder}, "gamma,alpha,delta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := newTestManager(testListedPlugins...).ListConsolePluginsPage(&tt.opts)
+			if err != nil {
+				t.Fatalf("ListConsolePluginsPage() error = %v", err)
+			}
+			if got := pageNames(page); got != tt.want {
+				t.Errorf("ListConsolePluginsPage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListConsolePluginsPagination(t *testing.T) {
+	cm := newTestManager(testListedPlugins...)
+	opts := &ListOptions{SortBy: SortByOrder, Limit: 3}
+	var pages []string
+	for i := 0; i < 3; i++ {
+		page, err := cm.ListConsolePluginsPage(opts)
+		if err != nil {
+			t.Fatalf("ListConsolePluginsPage() error = %v", err)
+		}
+		pages = append(pages, pageNames(page))
+		if page.Continue == "" {
+			if page.RemainingItemCount != nil {
+				t.Errorf("last page should not have a remaining item count")
+			}
+			break
+		}
+		if page.RemainingItemCount == nil || *page.RemainingItemCount != 1 {
+			t.Errorf("remaining item count = %v, want 1", page.RemainingItemCount)
+		}
+		opts.Continue = page.Continue
+		opts.Limit = 2
+	}
+	if got, want := strings.Join(pages, "|"), "beta,gamma,alpha|delta"; got != want {
+		t.Errorf("pages = %s, want %s", got, want)
+	}
+}
+
+func TestListConsolePluginsPageEffectiveEnablement(t *testing.T) {
+	plugins := append([]testConsolePlugin(nil), testListedPlugins[:3]...)
+	plugins[0].consoleVersion = ">=2.0"
+	cm := newTestManager(plugins...)
+	cm.ConsoleVersion = version.MustParseGeneric("1.2.0")
+	enabled, disabled := true, false
+	for _, tt := range []struct {
+		enabled *bool
+		want    string
+	}{{&enabled, "gamma"}, {&disabled, "alpha,beta"}} {
+		page, err := cm.ListConsolePluginsPage(&ListOptions{Enabled: tt.enabled})
+		if err != nil {
+			t.Fatalf("ListConsolePluginsPage() error = %v", err)
+		}
+		if got := pageNames(page); got != tt.want {
+			t.Errorf("ListConsolePluginsPage(enabled=%t) = %s, want %s", *tt.enabled, got, tt.want)
+		}
+	}
+}
+
+func TestListConsolePluginsPageLocalized(t *testing.T) {
+	plugins := append([]testConsolePlugin(nil), testListedPlugins...)
+	for i, displayName := range []string{"监控", "日志", "告警"} {
+		plugins[i].displayNames = map[string]string{"zh": displayName}
+	}
+	cm := newTestManager(plugins...)
+	zh := []language.Tag{language.Chinese}
+	tests := []struct {
+		name string
+		opts ListOptions
+		want string
+	}{
+		{"TestSearchLocalized", ListOptions{Search: "日志", Languages: zh}, "beta"},
+		{"TestSearchDefaultNotMatched", ListOptions{Search: "ing", Languages: zh}, ""},
+		{"TestSearchDefault", ListOptions{Search: "日志"}, ""},
+		// Dashboard sorts before the Chinese display names
+		{"TestSortLocalized", ListOptions{SortBy: SortByDisplayName, Languages: zh}, "delta,gamma,beta,alpha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := cm.ListConsolePluginsPage(&tt.opts)
+			if err != nil {
+				t.Fatalf("ListConsolePluginsPage() error = %v", err)
+			}
+			if got := pageNames(page); got != tt.want {
+				t.Errorf("ListConsolePluginsPage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListConsolePluginsPageVisible(t *testing.T) {
+	cm := newTestManager(testListedPlugins...)
+	opts := &ListOptions{
+		SortBy: SortByOrder,
+		Limit:  2,
+		Visible: func(cp *ConsolePlugin) (*ConsolePlugin, bool) {
+			return cp, cp.Name != "beta"
+		},
+	}
+	page, err := cm.ListConsolePluginsPage(opts)
+	if err != nil {
+		t.Fatalf("ListConsolePluginsPage() error = %v", err)
+	}
+	if got, want := pageNames(page), "gamma,alpha"; got != want {
+		t.Errorf("ListConsolePluginsPage() = %s, want %s", got, want)
+	}
+	if page.RemainingItemCount == nil || *page.RemainingItemCount != 1 {
+		t.Errorf("remaining item count = %v, want 1", page.RemainingItemCount)
+	}
+}
+
+func TestListConsolePluginsPagePassThrough(t *testing.T) {
+	cm := newTestManager(testListedPlugins...)
+	client := cm.Client.(interface{ Actions() []clienttesting.Action })
+	_, err := cm.ListConsolePluginsPage(&ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"tier": "Nav"}),
+		Limit:         1,
+		Continue:      "server-token",
+	})
+	if err != nil {
+		t.Fatalf("ListConsolePluginsPage() error = %v", err)
+	}
+	actions := client.Actions()
+	list, ok := actions[len(actions)-1].(clienttesting.ListActionImpl)
+	if !ok {
+		t.Fatalf("last action = %v, want list", actions[len(actions)-1])
+	}
+	if got := list.GetListRestrictions().Labels.String(); got != "tier=Nav" {
+		t.Errorf("list label selector = %q, want tier=Nav", got)
+	}
+}
+
+func TestListConsolePluginsPageInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{"TestUnsupportedSortBy", ListOptions{SortBy: "version"}},
+		{"TestNegativeLimit", ListOptions{Limit: -1}},
+		{"TestInvalidContinue", ListOptions{SortBy: SortByOrder, Continue: "not-a-token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestManager(testListedPlugins...).ListConsolePluginsPage(&tt.opts)
+			if !apierrors.IsBadRequest(err) {
+				t.Errorf("ListConsolePluginsPage() error = %v, want bad request", err)
+			}
+		})
+	}
+}
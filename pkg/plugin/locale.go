@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"sort"
+
+	"golang.org/x/text/language"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// PreferredLanguages returns the languages preferred by the client in descending order. The lang parameter
+// takes precedence over the Accept-Language header, malformed values are ignored.
+func PreferredLanguages(lang, acceptLanguage string) []language.Tag {
+	if lang != "" {
+		if tag, err := language.Parse(lang); err == nil {
+			return []language.Tag{tag}
+		}
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return nil
+	}
+	return tags
+}
+
+// LocalizeDisplayName returns the display name in the locale best matching the preferred languages,
+// or the default display name if none of the locales matches
+func LocalizeDisplayName(displayName string, displayNames map[string]string, preferred []language.Tag) string {
+	if len(displayNames) == 0 || len(preferred) == 0 {
+		return displayName
+	}
+	// the default display name is supported as the undetermined language so the matcher could fall back to it.
+	// The matcher breaks ties by the order of the supported languages, so the locales are sorted to always pick
+	// the same display name.
+	locales := make([]string, 0, len(displayNames))
+	for locale := range displayNames {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	supported := []language.Tag{language.Und}
+	names := []string{displayName}
+	for _, locale := range locales {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			continue
+		}
+		supported = append(supported, tag)
+		names = append(names, displayNames[locale])
+	}
+	_, index, confidence := language.NewMatcher(supported).Match(preferred...)
+	if confidence == language.No {
+		return displayName
+	}
+	return names[index]
+}
+
+// LocalizeSubPages returns a copy of the sub-pages whose display names are localized
+func LocalizeSubPages(subPages []ConsolePluginName, preferred []language.Tag) []ConsolePluginName {
+	if subPages == nil {
+		return nil
+	}
+	localized := make([]ConsolePluginName, 0, len(subPages))
+	for _, page := range subPages {
+		localized = append(localized, ConsolePluginName{
+			PageName:    page.PageName,
+			DisplayName: LocalizeDisplayName(page.DisplayName, page.DisplayNames, preferred),
+		})
+	}
+	return localized
+}
+
+func validateDisplayNames(displayNames map[string]string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for locale, displayName := range displayNames {
+		if _, err := language.Parse(locale); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(locale), locale, "must be a BCP-47 language tag"))
+			continue
+		}
+		allErrs = append(allErrs, validateDisplayName(displayName, fldPath.Key(locale))...)
+	}
+	return allErrs
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"testing"
+)
+
+func TestLocalizeDisplayName(t *testing.T) {
+	displayNames := map[string]string{
+		"zh-CN": "监控",
+		"en":    "Monitoring",
+		"en-GB": "Monitoring (UK)",
+	}
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		displayNames   map[string]string
+		want           string
+	}{
+		{"TestNoPreference", "", "", displayNames, "Default"},
+		{"TestExact", "", "zh-CN", displayNames, "监控"},
+		{"TestRegionFallback", "", "en-US", displayNames, "Monitoring"},
+		{"TestMostSpecific", "", "en-GB", displayNames, "Monitoring (UK)"},
+		{"TestScriptMatch", "", "zh-Hans", displayNames, "监控"},
+		{"TestQuality", "", "fr;q=0.9, zh-CN;q=0.8, en;q=0.5", displayNames, "监控"},
+		{"TestUnmatched", "", "fr, de", displayNames, "Default"},
+		{"TestLangOverrides", "en", "zh-CN", displayNames, "Monitoring"},
+		{"TestMalformedLang", "not a locale", "zh-CN", displayNames, "监控"},
+		{"TestMalformedAcceptLanguage", "", ";;;", displayNames, "Default"},
+		{"TestNoDisplayNames", "", "zh-CN", nil, "Default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preferred := PreferredLanguages(tt.lang, tt.acceptLanguage)
+			if got := LocalizeDisplayName("Default", tt.displayNames, preferred); got != tt.want {
+				t.Errorf("LocalizeDisplayName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeDisplayNameTiedLocales(t *testing.T) {
+	// the preferred languages match several locales equally, the map order must not decide between them
+	displayNames := map[string]string{
+		"zh":         "监控",
+		"zh-Hans":    "监控 (简体)",
+		"zh-CN":      "监控 (中国)",
+		"zh-Hans-CN": "监控 (简体, 中国)",
+	}
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"TestTiedTraditional", "zh-TW", "监控"},
+		{"TestTiedHongKong", "zh-HK", "监控"},
+		{"TestTiedSingapore", "zh-SG", "监控"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preferred := PreferredLanguages("", tt.acceptLanguage)
+			for i := 0; i < 50; i++ {
+				if got := LocalizeDisplayName("Default", displayNames, preferred); got != tt.want {
+					t.Fatalf("LocalizeDisplayName() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalizeSubPages(t *testing.T) {
+	subPages := []ConsolePluginName{
+		{PageName: "alerts", DisplayName: "Alerts", DisplayNames: map[string]string{"zh-CN": "告警"}},
+		{PageName: "rules", DisplayName: "Rules"},
+	}
+	got := LocalizeSubPages(subPages, PreferredLanguages("zh-CN", ""))
+	if len(got) != 2 || got[0].DisplayName != "告警" || got[1].DisplayName != "Rules" {
+		t.Errorf("LocalizeSubPages() = %v", got)
+	}
+	if got[0].DisplayNames != nil || subPages[0].DisplayName != "Alerts" {
+		t.Errorf("LocalizeSubPages() should return a localized copy without the display names")
+	}
+	if LocalizeSubPages(nil, nil) != nil {
+		t.Errorf("LocalizeSubPages(nil) should be nil")
+	}
+}
@@ -13,21 +13,46 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 
+	jsonpatch "github.com/evanphx/json-patch"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"plugin-management-service/pkg/zlog"
 )
 
-// ConsolePluginManager contains a client to access ConsolePlugin resources
+// ErrCacheNotSynced is returned on reads before the ConsolePlugin cache has synced with the API server
+var ErrCacheNotSynced = errors.New("consoleplugin cache has not synced yet")
+
+// ConsolePluginManager contains a client to access ConsolePlugin resources.
+// Reads are served from an informer cache once the manager is started, writes always go to the API server.
+// A manager without informer (e.g. only Client is set) reads from the API server directly.
 type ConsolePluginManager struct {
 	Client dynamic.Interface
+
+	// ConsoleVersion is the version of the running console, ConsolePlugins not supporting it are kept disabled
+	ConsoleVersion *version.Version
+
+	informerFactory dynamicinformer.DynamicSharedInformerFactory
+	informer        cache.SharedIndexInformer
+	lister          cache.GenericLister
 }
 
 // NewConsolePluginManager returns a new ConsolePluginManager
@@ -37,51 +62,277 @@ func NewConsolePluginManager(config *rest.Config) (*ConsolePluginManager, error)
 		return nil, err
 	}
 
+	return newConsolePluginManager(client), nil
+}
+
+func newConsolePluginManager(client dynamic.Interface) *ConsolePluginManager {
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, defaultResyncPeriod)
+	genericInformer := informerFactory.ForResource(consolePluginGVR)
 	return &ConsolePluginManager{
-		Client: client,
-	}, nil
+		Client:          client,
+		informerFactory: informerFactory,
+		informer:        genericInformer.Informer(),
+		lister:          genericInformer.Lister(),
+	}
+}
+
+// Start starts the informer of the manager, the cache is kept in sync until the context is done
+func (cm *ConsolePluginManager) Start(ctx context.Context) {
+	if cm.informerFactory == nil {
+		return
+	}
+	cm.informerFactory.Start(ctx.Done())
+}
+
+// HasSynced returns whether reads of the manager are ready to be served
+func (cm *ConsolePluginManager) HasSynced() bool {
+	return cm.informer == nil || cm.informer.HasSynced()
+}
+
+// WaitForCacheSync blocks until the cache has synced or the context is done
+func (cm *ConsolePluginManager) WaitForCacheSync(ctx context.Context) bool {
+	if cm.informer == nil {
+		return true
+	}
+	return cache.WaitForCacheSync(ctx.Done(), cm.informer.HasSynced)
+}
+
+// Informer returns the shared ConsolePlugin informer of the manager
+func (cm *ConsolePluginManager) Informer() cache.SharedIndexInformer {
+	return cm.informer
 }
 
 // ListConsolePlugins returns all the ConsolePlugin in the cluster
 func (cm *ConsolePluginManager) ListConsolePlugins() ([]ConsolePlugin, error) {
-	return ListConsolePlugins(cm.Client)
+	return cm.ListConsolePluginsBySelector(labels.Everything())
+}
+
+// ListConsolePluginsBySelector returns the ConsolePlugins whose labels match the selector
+func (cm *ConsolePluginManager) ListConsolePluginsBySelector(selector labels.Selector) ([]ConsolePlugin, error) {
+	if cm.lister == nil {
+		consolePlugins, err := ListConsolePlugins(cm.Client)
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]ConsolePlugin, 0, len(consolePlugins))
+		for _, cp := range consolePlugins {
+			if selector.Matches(labels.Set(cp.Labels)) {
+				matched = append(matched, cp)
+			}
+		}
+		return matched, nil
+	}
+	if !cm.HasSynced() {
+		return nil, ErrCacheNotSynced
+	}
+
+	objs, err := cm.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	consolePlugins := make([]ConsolePlugin, 0, len(objs))
+	for _, obj := range objs {
+		consolePlugin, err := consolePluginFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		consolePlugins = append(consolePlugins, *consolePlugin)
+	}
+	// keep the same order as a list from the API server
+	sort.Slice(consolePlugins, func(i, j int) bool {
+		return consolePlugins[i].Name < consolePlugins[j].Name
+	})
+	return consolePlugins, nil
+}
+
+// listConsolePluginsForWrite returns all the ConsolePlugins which a write is defaulted and validated against, read
+// from the API server until the cache has synced, so that the writes are not rejected while the service starts
+func (cm *ConsolePluginManager) listConsolePluginsForWrite() ([]ConsolePlugin, error) {
+	consolePlugins, err := cm.ListConsolePlugins()
+	if errors.Is(err, ErrCacheNotSynced) {
+		return ListConsolePlugins(cm.Client)
+	}
+	return consolePlugins, err
 }
 
 // GetConsolePlugin returns the ConsolePlugin with given name
 func (cm *ConsolePluginManager) GetConsolePlugin(pluginName string) (*ConsolePlugin, error) {
-	return GetConsolePlugin(cm.Client, pluginName)
+	if cm.lister == nil {
+		return GetConsolePlugin(cm.Client, pluginName)
+	}
+	if !cm.HasSynced() {
+		return nil, ErrCacheNotSynced
+	}
+
+	obj, err := cm.lister.Get(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	return consolePluginFromObject(obj)
+}
+
+// WatchConsolePlugins watches ConsolePlugin resources on the API server starting from the given resourceVersion.
+// An empty resourceVersion starts with synthetic ADDED events for all the existing ConsolePlugin.
+func (cm *ConsolePluginManager) WatchConsolePlugins(ctx context.Context,
+	resourceVersion string) (watch.Interface, error) {
+	return cm.Client.Resource(consolePluginGVR).Watch(ctx, metav1.ListOptions{
+		ResourceVersion:     resourceVersion,
+		AllowWatchBookmarks: true,
+	})
 }
 
 // CheckPluginInstallment checks whether the ConsolePlugin with given name is installed
 func (cm *ConsolePluginManager) CheckPluginInstallment(pluginName string) bool {
-	_, err := GetConsolePlugin(cm.Client, pluginName)
+	_, err := cm.GetConsolePlugin(pluginName)
 	return err == nil
 }
 
-// CheckPluginEnablementIfInstalled checks the enablement of an installed ConsolePlugin
+// CheckPluginEnablementIfInstalled checks the enablement of an installed ConsolePlugin.
+// A ConsolePlugin incompatible with the console version is never enabled.
 func (cm *ConsolePluginManager) CheckPluginEnablementIfInstalled(pluginName string) (bool, error) {
-	cp, err := GetConsolePlugin(cm.Client, pluginName)
+	cp, err := cm.GetConsolePlugin(pluginName)
 	if err != nil {
 		return false, err
 	}
-	return cp.Spec.Enabled, nil
+	return cp.Spec.Enabled && cm.CheckCompatibility(cp) == nil, nil
 }
 
-// SetPluginEnablementIfInstalled sets the enablement of the ConsolePlugin with given name
+// SetPluginEnablementIfInstalled sets the enablement of the ConsolePlugin with given name.
+// It is refused if the dependencies of the ConsolePlugin would be broken, see SetPluginEnablement.
+// The latest ConsolePlugin is read and patched with its resourceVersion as precondition, retrying on conflict.
 func (cm *ConsolePluginManager) SetPluginEnablementIfInstalled(pluginName string, newEnabled bool) error {
+	var changed []string
+	err := RetryOnConflict(func() error {
+		cp, err := GetConsolePlugin(cm.Client, pluginName)
+		if err != nil {
+			return err
+		}
+		changed, err = cm.SetPluginEnablementIfMatch(pluginName, newEnabled, false, cp.ResourceVersion)
+		return err
+	})
+	if err == nil && len(changed) == 0 {
+		zlog.Infof("ConsolePlugin enabled already satisfied: %t, skip patching", newEnabled)
+	}
+	return err
+}
+
+// CreateConsolePlugin validates the spec and creates a ConsolePlugin named after its PluginName
+func (cm *ConsolePluginManager) CreateConsolePlugin(spec *ConsolePluginSpec) (*ConsolePlugin, error) {
+	cp := &ConsolePlugin{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: consolePluginGVR.GroupVersion().String(),
+			Kind:       consolePluginKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: spec.PluginName,
+		},
+		Spec: *spec,
+	}
+	if err := cm.Default(cp, nil); err != nil {
+		return nil, err
+	}
+	if errs := cm.Validate(cp); len(errs) > 0 {
+		return nil, NewInvalidError(cp.Name, errs)
+	}
+
+	u, err := consolePluginToUnstructured(cp)
+	if err != nil {
+		return nil, err
+	}
+	created, err := cm.Client.Resource(consolePluginGVR).Create(context.Background(), u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return consolePluginFromObject(created)
+}
+
+// UpdateConsolePlugin validates the spec and replaces the spec of the ConsolePlugin with given name
+func (cm *ConsolePluginManager) UpdateConsolePlugin(pluginName string,
+	spec *ConsolePluginSpec) (*ConsolePlugin, error) {
 	cp, err := GetConsolePlugin(cm.Client, pluginName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	old := *cp
+	cp.Spec = *spec
+	return cm.updateConsolePlugin(cp, &old)
+}
+
+// PatchConsolePluginSpec applies a JSON merge patch to the spec of the ConsolePlugin with given name,
+// the patched spec is validated before being written
+func (cm *ConsolePluginManager) PatchConsolePluginSpec(pluginName string, patch []byte) (*ConsolePlugin, error) {
+	cp, err := GetConsolePlugin(cm.Client, pluginName)
+	if err != nil {
+		return nil, err
+	}
+	original, err := json.Marshal(&cp.Spec)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid merge patch: %v", err))
 	}
 
-	if cp.Spec.Enabled == newEnabled {
-		zlog.Infof("ConsolePlugin enabled already satisfied: %t, skip patching", cp.Spec.Enabled)
-		return nil
+	var spec ConsolePluginSpec
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&spec); err != nil {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid patched spec: %v", err))
 	}
+	old := *cp
+	cp.Spec = spec
+	return cm.updateConsolePlugin(cp, &old)
+}
 
-	patch := []byte(fmt.Sprintf(`{"spec": {"enabled": %t}}`, newEnabled))
-	err = PatchConsolePlugin(cm.Client, pluginName, patch)
-	return err
+func (cm *ConsolePluginManager) updateConsolePlugin(cp *ConsolePlugin, old *ConsolePlugin) (*ConsolePlugin, error) {
+	if err := cm.Default(cp, old); err != nil {
+		return nil, err
+	}
+	if errs := cm.Validate(cp); len(errs) > 0 {
+		return nil, NewInvalidError(cp.Name, errs)
+	}
+	u, err := consolePluginToUnstructured(cp)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := cm.Client.Resource(consolePluginGVR).Update(context.Background(), u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return consolePluginFromObject(updated)
+}
+
+// Validate validates the ConsolePlugin on its own and against the other ConsolePlugins in the cluster.
+// It is shared by the REST API and the validating admission webhook.
+func (cm *ConsolePluginManager) Validate(cp *ConsolePlugin) field.ErrorList {
+	allErrs := ValidateConsolePlugin(cp)
+
+	pluginNamePath := field.NewPath("spec", "pluginName")
+	consolePlugins, err := cm.listConsolePluginsForWrite()
+	if err != nil {
+		return append(allErrs, field.InternalError(pluginNamePath, err))
+	}
+	for _, other := range consolePlugins {
+		if other.Name != cp.Name && other.Spec.PluginName == cp.Spec.PluginName {
+			allErrs = append(allErrs, field.Duplicate(pluginNamePath, cp.Spec.PluginName))
+			break
+		}
+	}
+
+	// dependencies may be installed later, but must not form a cycle with the installed ones
+	g := newDependencyGraph(consolePlugins)
+	g[cp.Name] = cp
+	if _, _, err = g.dependencyOrder(cp.Name); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "dependencies"), cp.Spec.Dependencies,
+			err.Error()))
+	}
+	return allErrs
+}
+
+// DeleteConsolePlugin deletes the ConsolePlugin with given name
+func (cm *ConsolePluginManager) DeleteConsolePlugin(pluginName string) error {
+	return cm.Client.Resource(consolePluginGVR).Delete(context.Background(), pluginName, metav1.DeleteOptions{})
 }
 
 const (
@@ -94,6 +345,11 @@ var (
 		Version:  "v1beta1",
 		Resource: "consoleplugins",
 	}
+
+	consolePluginGK = schema.GroupKind{
+		Group: consolePluginGVR.Group,
+		Kind:  consolePluginKind,
+	}
 )
 
 // ListConsolePlugins returns all the ConsolePlugin in the cluster
@@ -130,9 +386,39 @@ func GetConsolePlugin(c dynamic.Interface, name string) (*ConsolePlugin, error)
 	return &consolePlugin, nil
 }
 
+func consolePluginFromObject(obj runtime.Object) (*ConsolePlugin, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	var consolePlugin ConsolePlugin
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &consolePlugin)
+	if err != nil {
+		zlog.Errorf("Error converting to %s: %s", consolePluginKind, u.GetName())
+		return nil, err
+	}
+	return &consolePlugin, nil
+}
+
+func consolePluginToUnstructured(cp *ConsolePlugin) (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cp)
+	if err != nil {
+		zlog.Errorf("Error converting %s %s to unstructured", consolePluginKind, cp.Name)
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
 // PatchConsolePlugin updates the ConsolePlugin with given patch data
 func PatchConsolePlugin(c dynamic.Interface, name string, data []byte) error {
 	_, err := c.Resource(consolePluginGVR).
 		Patch(context.Background(), name, "application/merge-patch+json", data, metav1.PatchOptions{}, "")
 	return err
 }
+
+// PatchConsolePluginStatus updates the status subresource of the ConsolePlugin with given patch data
+func PatchConsolePluginStatus(ctx context.Context, c dynamic.Interface, name string, data []byte) error {
+	_, err := c.Resource(consolePluginGVR).
+		Patch(ctx, name, "application/merge-patch+json", data, metav1.PatchOptions{}, "status")
+	return err
+}
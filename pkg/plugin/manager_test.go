@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var testService = map[string]interface{}{
+	"name":      "test-svc",
+	"namespace": "test-ns",
+}
+
+// testConsolePlugin is a ConsolePlugin of the tests, which set only the fields they exercise. It is enabled unless
+// disabled and has no order unless set.
+type testConsolePlugin struct {
+	name            string
+	displayName     string
+	displayNames    map[string]string
+	entrypoint      string
+	order           *int64
+	disabled        bool
+	dependencies    []string
+	consoleVersion  string
+	labels          map[string]string
+	release         string
+	resourceVersion string
+}
+
+func testOrder(order int64) *int64 {
+	return &order
+}
+
+// unstructured returns the ConsolePlugin as served by the API server
+func (p testConsolePlugin) unstructured() *unstructured.Unstructured {
+	u := newTestConsolePluginUnstructured(p.name, testService)
+	_ = unstructured.SetNestedField(u.Object, !p.disabled, "spec", "enabled")
+	if p.displayName != "" {
+		_ = unstructured.SetNestedField(u.Object, p.displayName, "spec", "displayName")
+	}
+	if p.displayNames != nil {
+		_ = unstructured.SetNestedStringMap(u.Object, p.displayNames, "spec", "displayNames")
+	}
+	if p.entrypoint != "" {
+		_ = unstructured.SetNestedField(u.Object, p.entrypoint, "spec", "entrypoint")
+	}
+	if p.order != nil {
+		_ = unstructured.SetNestedField(u.Object, *p.order, "spec", "order")
+	}
+	if len(p.dependencies) > 0 {
+		_ = unstructured.SetNestedStringSlice(u.Object, p.dependencies, "spec", "dependencies")
+	}
+	if p.consoleVersion != "" {
+		_ = unstructured.SetNestedField(u.Object, p.consoleVersion, "spec", "consoleVersion")
+	}
+	if p.labels != nil {
+		u.SetLabels(p.labels)
+	}
+	if p.release != "" {
+		u.SetAnnotations(map[string]string{ReleaseNameAnnotation: p.release})
+	}
+	if p.resourceVersion != "" {
+		u.SetResourceVersion(p.resourceVersion)
+	}
+	return u
+}
+
+// newTestManager returns a manager without cache of the ConsolePlugins
+func newTestManager(plugins ...testConsolePlugin) *ConsolePluginManager {
+	objs := make([]k8sruntime.Object, 0, len(plugins))
+	for _, p := range plugins {
+		objs = append(objs, p.unstructured())
+	}
+	return &ConsolePluginManager{Client: newFakeDynamicClient(objs...)}
+}
+
+func newStartedTestManager(t *testing.T) *ConsolePluginManager {
+	cm := newConsolePluginManager(newFakeDynamicClient(
+		newTestConsolePluginUnstructured("b-plugin", testService),
+		newTestConsolePluginUnstructured("a-plugin", testService),
+	))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cm.Start(ctx)
+	if !cm.WaitForCacheSync(ctx) {
+		t.Fatal("cache of the manager did not sync")
+	}
+	return cm
+}
+
+func TestConsolePluginManagerNotSynced(t *testing.T) {
+	cm := newConsolePluginManager(newFakeDynamicClient())
+	if cm.HasSynced() {
+		t.Fatal("cache of a manager that is not started should not be synced")
+	}
+	if _, err := cm.ListConsolePlugins(); !errors.Is(err, ErrCacheNotSynced) {
+		t.Errorf("ListConsolePlugins() error = %v, want %v", err, ErrCacheNotSynced)
+	}
+	if _, err := cm.GetConsolePlugin("a-plugin"); !errors.Is(err, ErrCacheNotSynced) {
+		t.Errorf("GetConsolePlugin() error = %v, want %v", err, ErrCacheNotSynced)
+	}
+}
+
+func TestConsolePluginManagerWritesNotSynced(t *testing.T) {
+	cm := newConsolePluginManager(newFakeDynamicClient(newTestConsolePluginUnstructured("a-plugin", testService)))
+	if cm.HasSynced() {
+		t.Fatal("cache of a manager that is not started should not be synced")
+	}
+
+	// the writes are checked against the ConsolePlugins of the API server until the cache has synced
+	cp := &ConsolePlugin{}
+	cp.Name = "b-plugin"
+	cp.Spec = ConsolePluginSpec{PluginName: "a-plugin", DisplayName: "B Plugin", Entrypoint: "Side",
+		Backend: &ConsolePluginBackend{Type: ServiceBackendType,
+			Service: &ConsolePluginService{Name: "test-svc", Namespace: "test-ns"}}}
+	if err := cm.Default(cp, nil); err != nil || cp.Spec.Order == nil {
+		t.Fatalf("Default() error = %v, order %v", err, cp.Spec.Order)
+	}
+	errs := cm.Validate(cp)
+	if len(errs) != 1 || errs[0].Type != field.ErrorTypeDuplicate {
+		t.Errorf("Validate() = %v, want the duplicate spec.pluginName only", errs)
+	}
+}
+
+func TestConsolePluginManagerCachedReads(t *testing.T) {
+	cm := newStartedTestManager(t)
+
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err != nil {
+		t.Fatalf("ListConsolePlugins() error = %v", err)
+	}
+	if len(consolePlugins) != 2 || consolePlugins[0].Name != "a-plugin" || consolePlugins[1].Name != "b-plugin" {
+		t.Errorf("ListConsolePlugins() = %v, want [a-plugin b-plugin]", consolePlugins)
+	}
+
+	cp, err := cm.GetConsolePlugin("b-plugin")
+	if err != nil || cp.Spec.PluginName != "b-plugin" {
+		t.Errorf("GetConsolePlugin() = %v, %v", cp, err)
+	}
+	if _, err = cm.GetConsolePlugin("not-installed"); !apierrors.IsNotFound(err) {
+		t.Errorf("GetConsolePlugin() error = %v, want not found", err)
+	}
+	if !cm.CheckPluginInstallment("a-plugin") {
+		t.Errorf("CheckPluginInstallment() = false, want true")
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	// ManifestFileName is the name of the manifest published under the BasePath of the plugin backend
+	ManifestFileName = "plugin-manifest.json"
+
+	maxManifestBytes = 1 << 20
+)
+
+// condition reasons of the ManifestValid condition
+const (
+	reasonBackendNotReachable = "BackendNotReachable"
+	reasonManifestFetchFailed = "ManifestFetchFailed"
+	reasonManifestInvalid     = "ManifestInvalid"
+	reasonManifestValid       = "ManifestValid"
+)
+
+var (
+	// ErrInvalidManifest is returned when the manifest published by the plugin backend is invalid
+	ErrInvalidManifest = errors.New("invalid plugin manifest")
+
+	entryScriptPattern   = regexp.MustCompile(`^[a-zA-Z0-9-_.][a-zA-Z0-9-_./]*\.js$`)
+	extensionTypePattern = regexp.MustCompile(`^[a-zA-Z0-9-.]+(/[a-zA-Z0-9-.]+)*$`)
+
+	// semverPattern matches a semantic version, the v prefix being tolerated
+	semverPattern = regexp.MustCompile(
+		`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+// PluginManifest describes the UI resources served by the backend of a ConsolePlugin
+type PluginManifest struct {
+	// Name is the name of the plugin, should be the same as the PluginName of the ConsolePlugin
+	Name string `json:"name"`
+
+	// Version is the semantic version of the plugin
+	Version string `json:"version"`
+
+	// EntryScript is the path of the entry script relative to the BasePath of the backend
+	EntryScript string `json:"entryScript"`
+
+	// Extensions are the extensions contributed by the plugin to the console
+	Extensions []PluginExtension `json:"extensions,omitempty"`
+
+	// ConsoleVersion is the range of console versions required by the plugin
+	ConsoleVersion string `json:"consoleVersion,omitempty"`
+}
+
+// PluginExtension is an extension contributed by a plugin to the console
+type PluginExtension struct {
+	// Type is the type of the extension point, e.g. console.page/route
+	Type string `json:"type"`
+
+	// Properties are the properties of the extension, interpreted by the console according to the type
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// ManifestFetcher fetches the manifests published by the backends of ConsolePlugins
+type ManifestFetcher struct {
+	httpClient *http.Client
+
+	// ConsoleVersion is the version of the running console, the manifests whose consoleVersion range excludes it
+	// are invalid. It is not checked if nil.
+	ConsoleVersion *version.Version
+}
+
+// NewManifestFetcher returns a new ManifestFetcher
+func NewManifestFetcher() *ManifestFetcher {
+	return &ManifestFetcher{
+		httpClient: &http.Client{
+			Timeout: defaultProbeTimeout,
+		},
+	}
+}
+
+// Fetch gets the manifest under the base URL of the backend of the ConsolePlugin and validates it.
+// The digest of the manifest is returned along with it. An error wrapping ErrInvalidManifest is
+// returned if the manifest could be fetched but is invalid.
+func (f *ManifestFetcher) Fetch(ctx context.Context, cp *ConsolePlugin, link string) (*PluginManifest, string, error) {
+	if link == "" {
+		return nil, "", fmt.Errorf("consoleplugin %s has no service backend", cp.Name)
+	}
+	manifestURL := strings.TrimSuffix(link, "/") + "/" + ManifestFileName
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP GET %s returned status code %d", manifestURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxManifestBytes {
+		return nil, "", fmt.Errorf("%w: larger than %d bytes", ErrInvalidManifest, maxManifestBytes)
+	}
+	manifest := &PluginManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+	if errs := ValidatePluginManifest(manifest, cp, f.ConsoleVersion); len(errs) > 0 {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidManifest, errs.ToAggregate())
+	}
+	return manifest, ManifestDigest(data), nil
+}
+
+// ManifestDigest returns the sha256 digest of the manifest content
+func ManifestDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ValidatePluginManifest validates the manifest published by the backend of the ConsolePlugin. The consoleVersion
+// range of the manifest must include the console version unless it is nil.
+func ValidatePluginManifest(manifest *PluginManifest, cp *ConsolePlugin,
+	consoleVersion *version.Version) field.ErrorList {
+	var allErrs field.ErrorList
+	if manifest.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("name"), ""))
+	} else if manifest.Name != cp.Spec.PluginName {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("name"), manifest.Name,
+			"must be the same as the pluginName of the ConsolePlugin"))
+	}
+
+	if manifest.Version == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("version"), ""))
+	} else if !semverPattern.MatchString(manifest.Version) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("version"), manifest.Version,
+			"must be a semantic version"))
+	}
+
+	entryScriptPath := field.NewPath("entryScript")
+	switch {
+	case manifest.EntryScript == "":
+		allErrs = append(allErrs, field.Required(entryScriptPath, ""))
+	case !entryScriptPattern.MatchString(manifest.EntryScript) || strings.Contains(manifest.EntryScript, ".."):
+		allErrs = append(allErrs, field.Invalid(entryScriptPath, manifest.EntryScript,
+			"must be a relative path of a .js file under the base path of the backend"))
+	}
+
+	for i, extension := range manifest.Extensions {
+		typePath := field.NewPath("extensions").Index(i).Child("type")
+		if extension.Type == "" {
+			allErrs = append(allErrs, field.Required(typePath, ""))
+		} else if !extensionTypePattern.MatchString(extension.Type) {
+			allErrs = append(allErrs, field.Invalid(typePath, extension.Type,
+				"should only include alphabets, digits, '-', '.' and '/'"))
+		}
+	}
+
+	if manifest.ConsoleVersion != "" {
+		consoleVersionPath := field.NewPath("consoleVersion")
+		r, err := ParseVersionRange(manifest.ConsoleVersion)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(consoleVersionPath, manifest.ConsoleVersion, err.Error()))
+		} else if consoleVersion != nil && !r.Contains(consoleVersion) {
+			allErrs = append(allErrs, field.Invalid(consoleVersionPath, manifest.ConsoleVersion,
+				fmt.Sprintf("does not include the console version %s", consoleVersion)))
+		}
+	}
+	return allErrs
+}
+
+// setManifestCondition records the result of fetching the manifest in the status of a ConsolePlugin.
+// The digest of the last valid manifest is kept if the backend could not be reached.
+func setManifestCondition(status *ConsolePluginStatus, cp *ConsolePlugin, reachable bool, digest string,
+	err error) {
+	condition := metav1.Condition{
+		Type:               ConditionManifestValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cp.Generation,
+		Reason:             reasonManifestValid,
+	}
+	switch {
+	case !reachable:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = reasonBackendNotReachable
+	case errors.Is(err, ErrInvalidManifest):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonManifestInvalid
+		condition.Message = err.Error()
+		status.ManifestDigest = ""
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonManifestFetchFailed
+		condition.Message = err.Error()
+		status.ManifestDigest = ""
+	default:
+		status.ManifestDigest = digest
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
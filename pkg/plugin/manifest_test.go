@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testManifest = `{
+	"name": "test-consoleplugin",
+	"version": "1.2.0",
+	"entryScript": "static/plugin-entry.js",
+	"extensions": [{"type": "console.page/route", "properties": {"path": "/test"}}]
+}`
+
+func newTestManifestServer(manifest string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ui/" + ManifestFileName:
+			_, _ = w.Write([]byte(manifest))
+		case "/ui":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestValidatePluginManifest(t *testing.T) {
+	tests := []struct {
+		name       string
+		manifest   PluginManifest
+		wantFields []string
+	}{
+		{
+			"TestValid",
+			PluginManifest{Name: "test-plugin", Version: "v1.0.0-rc.1", EntryScript: "plugin-entry.js"},
+			nil,
+		},
+		{
+			"TestMissingFields",
+			PluginManifest{},
+			[]string{"name", "version", "entryScript"},
+		},
+		{
+			"TestMismatchedName",
+			PluginManifest{Name: "other-plugin", Version: "1.0.0", EntryScript: "plugin-entry.js"},
+			[]string{"name"},
+		},
+		{
+			"TestInvalidVersionAndEntryScript",
+			PluginManifest{Name: "test-plugin", Version: "1.0", EntryScript: "../secret.js"},
+			[]string{"version", "entryScript"},
+		},
+		{
+			"TestAbsoluteEntryScript",
+			PluginManifest{Name: "test-plugin", Version: "1.0.0", EntryScript: "https://example.com/entry.js"},
+			[]string{"entryScript"},
+		},
+		{
+			"TestCompatibleConsoleVersion",
+			PluginManifest{Name: "test-plugin", Version: "1.0.0", EntryScript: "plugin-entry.js",
+				ConsoleVersion: ">=1.0 <2.0"},
+			nil,
+		},
+		{
+			"TestIncompatibleConsoleVersion",
+			PluginManifest{Name: "test-plugin", Version: "1.0.0", EntryScript: "plugin-entry.js",
+				ConsoleVersion: ">=2.0"},
+			[]string{"consoleVersion"},
+		},
+		{
+			"TestInvalidConsoleVersion",
+			PluginManifest{Name: "test-plugin", Version: "1.0.0", EntryScript: "plugin-entry.js",
+				ConsoleVersion: ">=latest"},
+			[]string{"consoleVersion"},
+		},
+		{
+			"TestInvalidExtension",
+			PluginManifest{Name: "test-plugin", Version: "1.0.0", EntryScript: "plugin-entry.js",
+				Extensions: []PluginExtension{{Type: "console page"}, {}}},
+			[]string{"extensions[0].type", "extensions[1].type"},
+		},
+	}
+	cp := &ConsolePlugin{Spec: ConsolePluginSpec{PluginName: "test-plugin"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidatePluginManifest(&tt.manifest, cp, version.MustParseGeneric("1.2.0"))
+			if got := errorFields(errs); strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("ValidatePluginManifest() fields = %v, want %v (%v)", got, tt.wantFields, errs)
+			}
+		})
+	}
+}
+
+func TestManifestFetcherFetch(t *testing.T) {
+	cp := &ConsolePlugin{Spec: ConsolePluginSpec{PluginName: "test-consoleplugin"}}
+	tests := []struct {
+		name        string
+		manifest    string
+		path        string
+		wantErr     bool
+		wantInvalid bool
+	}{
+		{"TestValid", testManifest, "/ui", false, false},
+		{"TestTrailingSlash", testManifest, "/ui/", false, false},
+		{"TestNotFound", testManifest, "/other", true, false},
+		{"TestMalformed", "{", "/ui", true, true},
+		{"TestInvalid", `{"name": "other-plugin"}`, "/ui", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestManifestServer(tt.manifest)
+			defer server.Close()
+
+			manifest, digest, err := NewManifestFetcher().Fetch(context.Background(), cp, server.URL+tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrInvalidManifest) != tt.wantInvalid {
+				t.Errorf("Fetch() error = %v, want invalid manifest %t", err, tt.wantInvalid)
+			}
+			if err != nil {
+				return
+			}
+			if manifest.EntryScript != "static/plugin-entry.js" || len(manifest.Extensions) != 1 {
+				t.Errorf("Fetch() manifest = %+v", manifest)
+			}
+			if digest != ManifestDigest([]byte(testManifest)) {
+				t.Errorf("Fetch() digest = %s", digest)
+			}
+		})
+	}
+
+	if _, _, err := NewManifestFetcher().Fetch(context.Background(), cp, ""); err == nil {
+		t.Errorf("Fetch() without backend should fail")
+	}
+}
+
+func TestStatusReconcilerReconcileManifest(t *testing.T) {
+	server := newTestManifestServer(testManifest)
+	defer server.Close()
+
+	obj := newTestConsolePluginUnstructured("test-consoleplugin", map[string]interface{}{
+		"name":      "test-svc",
+		"namespace": "test-ns",
+	})
+	client := newFakeDynamicClient(obj)
+	prober := NewBackendProber(kubefake.NewSimpleClientset(newTestService("test-svc", "test-ns", DefaultServicePort)))
+	prober.resolveURL = func(cp *ConsolePlugin) string {
+		return server.URL + "/ui"
+	}
+	r := newStatusReconciler(client, newConsolePluginManager(client).Informer(), prober, NewManifestFetcher())
+	if err := r.informer.GetIndexer().Add(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reconcile(context.Background(), "test-consoleplugin"); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	cp, err := GetConsolePlugin(client, "test-consoleplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ManifestDigest([]byte(testManifest)); cp.Status.ManifestDigest != want {
+		t.Errorf("status manifestDigest = %q, want %q", cp.Status.ManifestDigest, want)
+	}
+	if !meta.IsStatusConditionTrue(cp.Status.Conditions, ConditionManifestValid) {
+		t.Errorf("ManifestValid condition should be true: %v", cp.Status.Conditions)
+	}
+}
+
+func TestSetManifestCondition(t *testing.T) {
+	cp := &ConsolePlugin{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+	status := &ConsolePluginStatus{ManifestDigest: "sha256:old"}
+
+	setManifestCondition(status, cp, false, "", nil)
+	if c := meta.FindStatusCondition(status.Conditions, ConditionManifestValid); c.Status != metav1.ConditionUnknown ||
+		status.ManifestDigest != "sha256:old" {
+		t.Errorf("unreachable backend should keep the digest: %+v %v", c, status.ManifestDigest)
+	}
+
+	setManifestCondition(status, cp, true, "", ErrInvalidManifest)
+	if c := meta.FindStatusCondition(status.Conditions, ConditionManifestValid); c.Reason != reasonManifestInvalid ||
+		status.ManifestDigest != "" {
+		t.Errorf("invalid manifest should clear the digest: %+v %v", c, status.ManifestDigest)
+	}
+
+	setManifestCondition(status, cp, true, "sha256:new", nil)
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionManifestValid) || status.ManifestDigest != "sha256:new" {
+		t.Errorf("valid manifest should record the digest: %v", status.ManifestDigest)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+// orderPatch is a merge patch of the order of a ConsolePlugin. The resourceVersion in the patch is a
+// precondition, the patch fails with a conflict if the ConsolePlugin was changed in between.
+type orderPatch struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Spec struct {
+		Order *int64 `json:"order"`
+	} `json:"spec"`
+}
+
+// appliedOrder records a patched order so that it could be rolled back
+type appliedOrder struct {
+	name     string
+	oldOrder *int64
+	newOrder int64
+}
+
+// SortConsolePluginsByOrder sorts ConsolePlugins as they are placed in the menu, ConsolePlugins without an
+// order are placed last. Ties are broken by name.
+func SortConsolePluginsByOrder(consolePlugins []ConsolePlugin) {
+	sort.SliceStable(consolePlugins, func(i, j int) bool {
+		oi, oj := consolePlugins[i].Spec.Order, consolePlugins[j].Spec.Order
+		switch {
+		case oi != nil && oj != nil && *oi != *oj:
+			return *oi < *oj
+		case (oi == nil) != (oj == nil):
+			return oi != nil
+		default:
+			return consolePlugins[i].Name < consolePlugins[j].Name
+		}
+	})
+}
+
+// ReorderConsolePlugins places the ConsolePlugins with given names first in the menu in the given order,
+// followed by the other ConsolePlugins in their current order, and returns the resulting menu order.
+// The orders are patched with resourceVersion preconditions, the patches already applied are rolled back
+// if a later one fails.
+func (cm *ConsolePluginManager) ReorderConsolePlugins(pluginNames []string) ([]ConsolePlugin, error) {
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err != nil {
+		return nil, err
+	}
+	SortConsolePluginsByOrder(consolePlugins)
+	byName := make(map[string]*ConsolePlugin, len(consolePlugins))
+	for i := range consolePlugins {
+		byName[consolePlugins[i].Name] = &consolePlugins[i]
+	}
+
+	var allErrs field.ErrorList
+	listed := make(map[string]struct{}, len(pluginNames))
+	for i, name := range pluginNames {
+		idxPath := field.NewPath("pluginNames").Index(i)
+		if _, ok := listed[name]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath, name))
+		} else if _, ok = byName[name]; !ok {
+			allErrs = append(allErrs, field.NotFound(idxPath, name))
+		}
+		listed[name] = struct{}{}
+	}
+	if len(pluginNames) == 0 {
+		allErrs = append(allErrs, field.Required(field.NewPath("pluginNames"), ""))
+	}
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(consolePluginGK, "order", allErrs)
+	}
+
+	ordered := make([]*ConsolePlugin, 0, len(consolePlugins))
+	for _, name := range pluginNames {
+		ordered = append(ordered, byName[name])
+	}
+	for i := range consolePlugins {
+		if _, ok := listed[consolePlugins[i].Name]; !ok {
+			ordered = append(ordered, &consolePlugins[i])
+		}
+	}
+
+	var applied []appliedOrder
+	for i, cp := range ordered {
+		order := int64(i)
+		if cp.Spec.Order != nil && *cp.Spec.Order == order {
+			continue
+		}
+		resourceVersion, err := cm.patchOrder(cp.Name, &order, cp.ResourceVersion)
+		if err != nil {
+			zlog.Errorf("Error reordering ConsolePlugin %s, rolling back: %v", cp.Name, err)
+			if notRestored := cm.rollbackOrders(applied); len(notRestored) > 0 {
+				return nil, apierrors.NewInternalError(fmt.Errorf("%v, and the orders of ConsolePlugins %v "+
+					"could not be rolled back, the menu is partly reordered", err, notRestored))
+			}
+			return nil, err
+		}
+		applied = append(applied, appliedOrder{name: cp.Name, oldOrder: cp.Spec.Order, newOrder: order})
+		cp.Spec.Order = &order
+		cp.ResourceVersion = resourceVersion
+	}
+
+	result := make([]ConsolePlugin, 0, len(ordered))
+	for _, cp := range ordered {
+		result = append(result, *cp)
+	}
+	return result, nil
+}
+
+// rollbackOrders restores the orders patched before in reverse order, and returns the names of the
+// ConsolePlugins which could not be restored. The latest ConsolePlugin is read again on conflict, its order
+// is left alone if it was changed by someone else in between.
+func (cm *ConsolePluginManager) rollbackOrders(applied []appliedOrder) []string {
+	var notRestored []string
+	for i := len(applied) - 1; i >= 0; i-- {
+		a := applied[i]
+		err := RetryOnConflict(func() error {
+			cp, err := GetConsolePlugin(cm.Client, a.name)
+			if err != nil {
+				return err
+			}
+			if cp.Spec.Order == nil || *cp.Spec.Order != a.newOrder {
+				zlog.Warnf("Order of ConsolePlugin %s was changed in between, not rolled back", a.name)
+				return nil
+			}
+			_, err = cm.patchOrder(a.name, a.oldOrder, cp.ResourceVersion)
+			return err
+		})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			zlog.Errorf("Error rolling back the order of ConsolePlugin %s: %v", a.name, err)
+			notRestored = append(notRestored, a.name)
+		}
+	}
+	return notRestored
+}
+
+// patchOrder patches the order of the ConsolePlugin if it still has the given resourceVersion,
+// and returns the new resourceVersion
+func (cm *ConsolePluginManager) patchOrder(name string, order *int64, resourceVersion string) (string, error) {
+	patch := orderPatch{}
+	patch.Metadata.ResourceVersion = resourceVersion
+	patch.Spec.Order = order
+	data, err := json.Marshal(&patch)
+	if err != nil {
+		return "", err
+	}
+	patched, err := cm.Client.Resource(consolePluginGVR).
+		Patch(context.Background(), name, types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return "", fmt.Errorf("patching order of %s: %w", name, err)
+	}
+	return patched.GetResourceVersion(), nil
+}
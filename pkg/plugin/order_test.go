@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func menuOrder(t *testing.T, cm *ConsolePluginManager) string {
+	consolePlugins, err := cm.ListConsolePlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortConsolePluginsByOrder(consolePlugins)
+	var items []string
+	for _, cp := range consolePlugins {
+		order := "-"
+		if cp.Spec.Order != nil {
+			order = fmt.Sprint(*cp.Spec.Order)
+		}
+		items = append(items, cp.Name+":"+order)
+	}
+	return strings.Join(items, ",")
+}
+
+// testOrderedPlugins are the ConsolePlugins a and b, c tied after it, and d without an order
+var testOrderedPlugins = []testConsolePlugin{
+	{name: "a", order: testOrder(0)},
+	{name: "b", order: testOrder(1)},
+	{name: "c", order: testOrder(1)},
+	{name: "d"},
+}
+
+func TestReorderConsolePlugins(t *testing.T) {
+	tests := []struct {
+		name        string
+		pluginNames []string
+		wantMenu    string
+		wantInvalid bool
+	}{
+		{"TestReorderAll", []string{"d", "c", "b", "a"}, "d:0,c:1,b:2,a:3", false},
+		{"TestReorderPartial", []string{"c"}, "c:0,a:1,b:2,d:3", false},
+		{"TestDuplicate", []string{"a", "a"}, "", true},
+		{"TestNotFound", []string{"a", "not-installed"}, "", true},
+		{"TestEmpty", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestManager(testOrderedPlugins...)
+			result, err := cm.ReorderConsolePlugins(tt.pluginNames)
+			if tt.wantInvalid {
+				if !apierrors.IsInvalid(err) {
+					t.Errorf("ReorderConsolePlugins() error = %v, want invalid", err)
+				}
+				if got := menuOrder(t, cm); got != "a:0,b:1,c:1,d:-" {
+					t.Errorf("menu changed after invalid reorder: %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReorderConsolePlugins() error = %v", err)
+			}
+			if got := menuOrder(t, cm); got != tt.wantMenu {
+				t.Errorf("menu = %s, want %s", got, tt.wantMenu)
+			}
+			if len(result) != 4 || result[0].Name != tt.pluginNames[0] {
+				t.Errorf("ReorderConsolePlugins() = %v", result)
+			}
+		})
+	}
+}
+
+func TestReorderConsolePluginsRollback(t *testing.T) {
+	cm := newTestManager(testOrderedPlugins...)
+	client := cm.Client.(interface {
+		PrependReactor(verb, resource string, reaction clienttesting.ReactionFunc)
+	})
+	client.PrependReactor("patch", "consoleplugins", func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+		if action.(clienttesting.PatchAction).GetName() == "a" {
+			return true, nil, apierrors.NewConflict(consolePluginGVR.GroupResource(), "a",
+				fmt.Errorf("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	_, err := cm.ReorderConsolePlugins([]string{"d", "c", "b", "a"})
+	if !apierrors.IsConflict(err) {
+		t.Fatalf("ReorderConsolePlugins() error = %v, want conflict", err)
+	}
+	if got := menuOrder(t, cm); got != "a:0,b:1,c:1,d:-" {
+		t.Errorf("menu = %s, want the orders rolled back", got)
+	}
+}
+
+func TestReorderConsolePluginsRollbackRetry(t *testing.T) {
+	tests := []struct {
+		name              string
+		rollbackConflicts int
+		wantMenu          string
+		wantInternal      bool
+	}{
+		{"TestRollbackRetriedOnConflict", 1, "a:0,b:1,c:1,d:-", false},
+		{"TestRollbackFailed", 100, "a:0,d:0,b:1,c:1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestManager(testOrderedPlugins...)
+			client := cm.Client.(interface {
+				PrependReactor(verb, resource string, reaction clienttesting.ReactionFunc)
+			})
+			patchesOfD := 0
+			client.PrependReactor("patch", "consoleplugins",
+				func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+					switch action.(clienttesting.PatchAction).GetName() {
+					case "a":
+						return true, nil, apierrors.NewConflict(consolePluginGVR.GroupResource(), "a",
+							fmt.Errorf("the object has been modified"))
+					case "d":
+						// the first patch of d reorders it, the following ones roll it back
+						patchesOfD++
+						if patchesOfD > 1 && patchesOfD <= 1+tt.rollbackConflicts {
+							return true, nil, apierrors.NewConflict(consolePluginGVR.GroupResource(), "d",
+								fmt.Errorf("the object has been modified"))
+						}
+					}
+					return false, nil, nil
+				})
+
+			_, err := cm.ReorderConsolePlugins([]string{"d", "c", "b", "a"})
+			if apierrors.IsInternalError(err) != tt.wantInternal || (!tt.wantInternal && !apierrors.IsConflict(err)) {
+				t.Fatalf("ReorderConsolePlugins() error = %v, want internal error %t", err, tt.wantInternal)
+			}
+			if tt.wantInternal && !strings.Contains(err.Error(), "[d]") {
+				t.Errorf("ReorderConsolePlugins() error = %v, want d reported as not rolled back", err)
+			}
+			if got := menuOrder(t, cm); got != tt.wantMenu {
+				t.Errorf("menu = %s, want %s", got, tt.wantMenu)
+			}
+		})
+	}
+}
+
+func TestRollbackOrdersSkipsChangedOrder(t *testing.T) {
+	cm := newTestManager(testOrderedPlugins...)
+	// b is at 1 instead of the order 5 set by the reorder, someone else changed it in between
+	notRestored := cm.rollbackOrders([]appliedOrder{{name: "b", oldOrder: nil, newOrder: 5}})
+	if len(notRestored) != 0 {
+		t.Errorf("rollbackOrders() = %v, want nothing left", notRestored)
+	}
+	if got := menuOrder(t, cm); got != "a:0,b:1,c:1,d:-" {
+		t.Errorf("menu = %s, want b left alone", got)
+	}
+}
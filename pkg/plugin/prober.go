@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 5 * time.Second
+	maxProbeBodyBytes    = 1 << 20
+)
+
+// condition reasons of the backend conditions
+const (
+	reasonNoServiceBackend  = "NoServiceBackend"
+	reasonServiceNotFound   = "ServiceNotFound"
+	reasonPortNotExposed    = "PortNotExposed"
+	reasonServiceFound      = "ServiceFound"
+	reasonBackendUnresolved = "BackendUnresolved"
+	reasonHTTPGetFailed     = "HTTPGetFailed"
+	reasonHTTPGetSucceeded  = "HTTPGetSucceeded"
+	reasonBackendNotReady   = "BackendNotReady"
+	reasonBackendReady      = "BackendReady"
+)
+
+// ProbeResult is the result of probing the backend of a ConsolePlugin
+type ProbeResult struct {
+	// Resolved is true if the backend service exists and exposes the configured port
+	Resolved bool
+
+	// Reachable is true if the HTTP GET on the backend BasePath succeeded
+	Reachable bool
+
+	// Latency is the duration of the HTTP GET on the backend BasePath
+	Latency time.Duration
+
+	// Err is the first error encountered while probing
+	Err error
+}
+
+// portNotExposedError is returned when the backend service exists but does not expose the configured port
+type portNotExposedError struct {
+	namespace string
+	name      string
+	port      int32
+}
+
+func (e *portNotExposedError) Error() string {
+	return fmt.Sprintf("service %s/%s does not expose port %d", e.namespace, e.name, e.port)
+}
+
+// BackendProber checks whether the backend service of a ConsolePlugin is up
+type BackendProber struct {
+	kubeClient kubernetes.Interface
+	httpClient *http.Client
+
+	// resolveURL returns the URL which is probed with HTTP GET
+	resolveURL func(cp *ConsolePlugin) string
+}
+
+// NewBackendProber returns a new BackendProber
+func NewBackendProber(kubeClient kubernetes.Interface) *BackendProber {
+	return &BackendProber{
+		kubeClient: kubeClient,
+		httpClient: &http.Client{
+			Timeout: defaultProbeTimeout,
+		},
+		resolveURL: ConsolePluginLink,
+	}
+}
+
+// Probe checks the backend service referenced by the ConsolePlugin and sends an HTTP GET on its BasePath
+func (p *BackendProber) Probe(ctx context.Context, cp *ConsolePlugin) ProbeResult {
+	if cp.Spec.Backend == nil || cp.Spec.Backend.Service == nil {
+		return ProbeResult{Err: fmt.Errorf("consoleplugin %s has no service backend", cp.Name)}
+	}
+	backend := cp.Spec.Backend.Service
+	if err := p.resolveService(ctx, backend); err != nil {
+		return ProbeResult{Err: err}
+	}
+
+	result := ProbeResult{Resolved: true}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.resolveURL(cp), nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBodyBytes))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		result.Err = fmt.Errorf("HTTP GET %s returned status code %d", req.URL, resp.StatusCode)
+		return result
+	}
+	result.Reachable = true
+	return result
+}
+
+func (p *BackendProber) resolveService(ctx context.Context, backend *ConsolePluginService) error {
+	svc, err := p.kubeClient.CoreV1().Services(backend.Namespace).Get(ctx, backend.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	port := backend.Port
+	if port == 0 {
+		port = DefaultServicePort
+	}
+	for _, svcPort := range svc.Spec.Ports {
+		if svcPort.Port == port {
+			return nil
+		}
+	}
+	return &portNotExposedError{namespace: backend.Namespace, name: backend.Name, port: port}
+}
+
+// setBackendConditions records the probe result in the status of a ConsolePlugin
+func setBackendConditions(status *ConsolePluginStatus, cp *ConsolePlugin, result ProbeResult, now metav1.Time) {
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	}
+	status.LastProbeTime = &now
+	status.LatencyMilliseconds = result.Latency.Milliseconds()
+	status.LastError = errMsg
+
+	resolved := metav1.Condition{
+		Type:               ConditionBackendResolved,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cp.Generation,
+		Reason:             reasonServiceFound,
+	}
+	if !result.Resolved {
+		resolved.Status = metav1.ConditionFalse
+		resolved.Reason = reasonServiceNotFound
+		resolved.Message = errMsg
+		var portErr *portNotExposedError
+		if cp.Spec.Backend == nil || cp.Spec.Backend.Service == nil {
+			resolved.Reason = reasonNoServiceBackend
+		} else if errors.As(result.Err, &portErr) {
+			resolved.Reason = reasonPortNotExposed
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, resolved)
+
+	reachable := metav1.Condition{
+		Type:               ConditionBackendReachable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cp.Generation,
+		Reason:             reasonHTTPGetSucceeded,
+	}
+	if !result.Resolved {
+		reachable.Status = metav1.ConditionUnknown
+		reachable.Reason = reasonBackendUnresolved
+	} else if !result.Reachable {
+		reachable.Status = metav1.ConditionFalse
+		reachable.Reason = reasonHTTPGetFailed
+		reachable.Message = errMsg
+	}
+	meta.SetStatusCondition(&status.Conditions, reachable)
+
+	ready := metav1.Condition{
+		Type:               ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: cp.Generation,
+		Reason:             reasonBackendReady,
+	}
+	if !result.Resolved || !result.Reachable {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = reasonBackendNotReady
+		ready.Message = errMsg
+	}
+	meta.SetStatusCondition(&status.Conditions, ready)
+}
+
+// IsConsolePluginReady returns whether the Ready condition of the ConsolePlugin is true
+func IsConsolePluginReady(cp *ConsolePlugin) bool {
+	return meta.IsStatusConditionTrue(cp.Status.Conditions, ConditionReady)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestService(name, namespace string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: port}},
+		},
+	}
+}
+
+func TestBackendProberProbe(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+
+	tests := []struct {
+		name          string
+		backend       *ConsolePluginBackend
+		url           string
+		wantResolved  bool
+		wantReachable bool
+	}{
+		{
+			"TestNoBackend",
+			nil,
+			okServer.URL,
+			false,
+			false,
+		},
+		{
+			"TestServiceNotFound",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "missing", Namespace: "ns"},
+			},
+			okServer.URL,
+			false,
+			false,
+		},
+		{
+			"TestServicePortMismatch",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns", Port: 8080},
+			},
+			okServer.URL,
+			false,
+			false,
+		},
+		{
+			"TestBackendUnhealthy",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns"},
+			},
+			failServer.URL,
+			true,
+			false,
+		},
+		{
+			"TestBackendReachable",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns"},
+			},
+			okServer.URL,
+			true,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBackendProber(kubefake.NewSimpleClientset(newTestService("svc", "ns", DefaultServicePort)))
+			p.resolveURL = func(cp *ConsolePlugin) string {
+				return tt.url
+			}
+			cp := &ConsolePlugin{Spec: ConsolePluginSpec{Backend: tt.backend}}
+			got := p.Probe(context.Background(), cp)
+			if got.Resolved != tt.wantResolved || got.Reachable != tt.wantReachable {
+				t.Errorf("Probe() = %+v, want resolved %t reachable %t", got, tt.wantResolved, tt.wantReachable)
+			}
+			if (got.Err == nil) != tt.wantReachable {
+				t.Errorf("Probe() error = %v, want error %t", got.Err, !tt.wantReachable)
+			}
+		})
+	}
+}
+
+func TestSetBackendConditions(t *testing.T) {
+	cp := &ConsolePlugin{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec: ConsolePluginSpec{
+			Backend: &ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns"},
+			},
+		},
+	}
+	status := &ConsolePluginStatus{}
+
+	setBackendConditions(status, cp, ProbeResult{Resolved: true, Reachable: true}, metav1.Now())
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionReady) {
+		t.Errorf("Ready condition should be true after a successful probe")
+	}
+	if c := meta.FindStatusCondition(status.Conditions, ConditionReady); c.ObservedGeneration != 2 {
+		t.Errorf("Ready condition observedGeneration = %d, want 2", c.ObservedGeneration)
+	}
+
+	setBackendConditions(status, cp, ProbeResult{Resolved: true, Err: context.DeadlineExceeded}, metav1.Now())
+	if !meta.IsStatusConditionFalse(status.Conditions, ConditionBackendReachable) {
+		t.Errorf("BackendReachable condition should be false after a failed probe")
+	}
+	if status.LastError != context.DeadlineExceeded.Error() {
+		t.Errorf("LastError = %q, want %q", status.LastError, context.DeadlineExceeded.Error())
+	}
+
+	setBackendConditions(status, cp, ProbeResult{Err: context.Canceled}, metav1.Now())
+	if c := meta.FindStatusCondition(status.Conditions, ConditionBackendReachable); c.Status != metav1.ConditionUnknown {
+		t.Errorf("BackendReachable condition = %s, want Unknown", c.Status)
+	}
+
+	setBackendConditions(status, cp, ProbeResult{Err: &portNotExposedError{"ns", "svc", 8080}}, metav1.Now())
+	if c := meta.FindStatusCondition(status.Conditions, ConditionBackendResolved); c.Reason != reasonPortNotExposed {
+		t.Errorf("BackendResolved condition reason = %s, want %s", c.Reason, reasonPortNotExposed)
+	}
+	setBackendConditions(status, cp, ProbeResult{Err: context.Canceled}, metav1.Now())
+	if c := meta.FindStatusCondition(status.Conditions, ConditionBackendResolved); c.Reason != reasonServiceNotFound {
+		t.Errorf("BackendResolved condition reason = %s, want %s", c.Reason, reasonServiceNotFound)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"plugin-management-service/pkg/zlog"
+)
+
+const (
+	// DefaultServicePort is the port of the backend service if not specified
+	DefaultServicePort int32 = 80
+
+	// DefaultBasePath is the base path of the backend service if not specified
+	DefaultBasePath = "/"
+
+	defaultResyncPeriod = 10 * time.Minute
+	maxReconcileRetries = 5
+
+	// probeRecordPeriod bounds how stale the recorded probe time and latency get while the status is unchanged
+	probeRecordPeriod = defaultResyncPeriod
+)
+
+// ConsolePluginLink computes the URL with which the front-end loads the UI resource of the ConsolePlugin.
+// An empty string is returned if the ConsolePlugin has no service backend.
+func ConsolePluginLink(cp *ConsolePlugin) string {
+	if cp.Spec.Backend == nil || cp.Spec.Backend.Service == nil {
+		return ""
+	}
+	svc := cp.Spec.Backend.Service
+	if svc.Name == "" || svc.Namespace == "" {
+		return ""
+	}
+
+	port := svc.Port
+	if port == 0 {
+		port = DefaultServicePort
+	}
+	basePath := svc.BasePath
+	if basePath == "" {
+		basePath = DefaultBasePath
+	}
+	return fmt.Sprintf("http://%s.%s.svc:%d%s", svc.Name, svc.Namespace, port, basePath)
+}
+
+// StatusReconciler watches ConsolePlugin resources and keeps their status in line with the spec.
+// The backend of every ConsolePlugin is also probed periodically and recorded in the status conditions.
+type StatusReconciler struct {
+	client        dynamic.Interface
+	informer      cache.SharedIndexInformer
+	queue         workqueue.RateLimitingInterface
+	prober        *BackendProber
+	manifests     *ManifestFetcher
+	probeInterval time.Duration
+}
+
+// NewStatusReconciler returns a new StatusReconciler sharing the ConsolePlugin informer of the manager.
+// The informer is started by the manager.
+func NewStatusReconciler(config *rest.Config, manager *ConsolePluginManager) (*StatusReconciler, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	manifests := NewManifestFetcher()
+	manifests.ConsoleVersion = manager.ConsoleVersion
+	return newStatusReconciler(manager.Client, manager.Informer(), NewBackendProber(kubeClient), manifests), nil
+}
+
+func newStatusReconciler(client dynamic.Interface, informer cache.SharedIndexInformer,
+	prober *BackendProber, manifests *ManifestFetcher) *StatusReconciler {
+	r := &StatusReconciler{
+		client:        client,
+		informer:      informer,
+		queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		prober:        prober,
+		manifests:     manifests,
+		probeInterval: defaultProbeInterval,
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// status updates do not bump the generation, skip them to avoid probing in a loop
+			oldMeta, oldErr := meta.Accessor(oldObj)
+			newMeta, newErr := meta.Accessor(newObj)
+			if oldErr == nil && newErr == nil && oldMeta.GetGeneration() == newMeta.GetGeneration() {
+				return
+			}
+			r.enqueue(newObj)
+		},
+	})
+	if err != nil {
+		zlog.Errorf("Error adding %s event handler: %v", consolePluginKind, err)
+	}
+	return r
+}
+
+func (r *StatusReconciler) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		zlog.Errorf("Error getting key of %s: %v", consolePluginKind, err)
+		return
+	}
+	r.queue.Add(key)
+}
+
+// enqueueAll adds every cached ConsolePlugin to the queue so that its backend is probed again
+func (r *StatusReconciler) enqueueAll(ctx context.Context) {
+	for _, key := range r.informer.GetIndexer().ListKeys() {
+		r.queue.Add(key)
+	}
+}
+
+// Run starts the given number of workers once the informer has synced, and blocks until the context is done
+func (r *StatusReconciler) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer r.queue.ShutDown()
+
+	zlog.Infof("Starting %s status reconciler", consolePluginKind)
+	if !cache.WaitForCacheSync(ctx.Done(), r.informer.HasSynced) {
+		zlog.Errorf("Timed out waiting for %s cache to sync", consolePluginKind)
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, r.runWorker, time.Second)
+	}
+	go wait.UntilWithContext(ctx, r.enqueueAll, r.probeInterval)
+	<-ctx.Done()
+	zlog.Infof("Stopping %s status reconciler", consolePluginKind)
+}
+
+func (r *StatusReconciler) runWorker(ctx context.Context) {
+	for r.processNextItem(ctx) {
+	}
+}
+
+func (r *StatusReconciler) processNextItem(ctx context.Context) bool {
+	key, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+	defer r.queue.Done(key)
+
+	err := r.reconcile(ctx, key.(string))
+	if err == nil {
+		r.queue.Forget(key)
+		return true
+	}
+
+	if r.queue.NumRequeues(key) < maxReconcileRetries {
+		zlog.Warnf("Error reconciling %s %s, retrying: %v", consolePluginKind, key, err)
+		r.queue.AddRateLimited(key)
+		return true
+	}
+	zlog.Errorf("Dropping %s %s out of the queue: %v", consolePluginKind, key, err)
+	r.queue.Forget(key)
+	return true
+}
+
+func (r *StatusReconciler) reconcile(ctx context.Context, key string) error {
+	obj, exists, err := r.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+	cp, err := consolePluginFromObject(runtimeObj)
+	if err != nil {
+		return err
+	}
+
+	status := ConsolePluginStatus{
+		Link:           ConsolePluginLink(cp),
+		Conditions:     append([]metav1.Condition(nil), cp.Status.Conditions...),
+		ManifestDigest: cp.Status.ManifestDigest,
+	}
+	result := r.prober.Probe(ctx, cp)
+	now := metav1.Now()
+	setBackendConditions(&status, cp, result, now)
+
+	var digest string
+	if result.Reachable {
+		_, digest, err = r.manifests.Fetch(ctx, cp, r.prober.resolveURL(cp))
+	}
+	setManifestCondition(&status, cp, result.Reachable, digest, err)
+
+	// the probe time and latency alone are not worth a new resourceVersion on every probe, they are updated
+	// along with a change of the status or once the recorded probe is older than probeRecordPeriod
+	if !statusChanged(&cp.Status, &status) && !probeRecordDue(&cp.Status, now.Time) {
+		return nil
+	}
+	patch, err := statusPatch(&cp.Status, &status)
+	if err != nil {
+		return err
+	}
+	if err = PatchConsolePluginStatus(ctx, r.client, cp.Name, patch); err != nil {
+		return err
+	}
+	if cp.Status.Link != status.Link {
+		zlog.Infof("Updated %s %s status link to %s", consolePluginKind, cp.Name, status.Link)
+	}
+	if cp.Status.ManifestDigest != status.ManifestDigest {
+		zlog.Infof("%s %s manifest digest changed to %q", consolePluginKind, cp.Name, status.ManifestDigest)
+	}
+	if ready := meta.IsStatusConditionTrue(status.Conditions, ConditionReady); ready != IsConsolePluginReady(cp) {
+		zlog.Infof("%s %s ready changed to %t: %s", consolePluginKind, cp.Name, ready, status.LastError)
+	}
+	return nil
+}
+
+// statusChanged reports whether the status differs in anything but the probe time, the probe latency and the
+// transition times of the conditions
+func statusChanged(old, updated *ConsolePluginStatus) bool {
+	if old.Link != updated.Link || old.LastError != updated.LastError || old.ManifestDigest != updated.ManifestDigest ||
+		len(old.Conditions) != len(updated.Conditions) {
+		return true
+	}
+	for _, condition := range updated.Conditions {
+		oldCondition := meta.FindStatusCondition(old.Conditions, condition.Type)
+		if oldCondition == nil || oldCondition.Status != condition.Status ||
+			oldCondition.Reason != condition.Reason || oldCondition.Message != condition.Message ||
+			oldCondition.ObservedGeneration != condition.ObservedGeneration {
+			return true
+		}
+	}
+	return false
+}
+
+// probeRecordDue reports whether the recorded probe is too old to be kept while the status is unchanged
+func probeRecordDue(old *ConsolePluginStatus, now time.Time) bool {
+	return old.LastProbeTime == nil || now.Sub(old.LastProbeTime.Time) >= probeRecordPeriod
+}
+
+// statusPatch returns the JSON merge patch of the status, the fields left empty in the updated status are
+// removed explicitly since the merge patch keeps the fields it omits
+func statusPatch(old, updated *ConsolePluginStatus) ([]byte, error) {
+	oldFields, err := statusFields(old)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := statusFields(updated)
+	if err != nil {
+		return nil, err
+	}
+	for name := range oldFields {
+		if _, ok := fields[name]; !ok {
+			fields[name] = nil
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"status": fields,
+	})
+}
+
+func statusFields(status *ConsolePluginStatus) (map[string]interface{}, error) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestConsolePluginUnstructured(name string, service map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "console.openfuyao.com/v1beta1",
+			"kind":       "ConsolePlugin",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"pluginName":  name,
+				"displayName": "Test Plugin",
+				"entrypoint":  "Side",
+				"backend": map[string]interface{}{
+					"type":    "Service",
+					"service": service,
+				},
+				"enabled": true,
+			},
+		},
+	}
+}
+
+func newFakeDynamicClient(objects ...k8sruntime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		k8sruntime.NewScheme(),
+		map[schema.GroupVersionResource]string{consolePluginGVR: "ConsolePluginList"},
+		objects...,
+	)
+}
+
+func TestConsolePluginLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *ConsolePluginBackend
+		want    string
+	}{
+		{
+			"TestNilBackend",
+			nil,
+			"",
+		},
+		{
+			"TestNilService",
+			&ConsolePluginBackend{Type: ServiceBackendType},
+			"",
+		},
+		{
+			"TestDefaults",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns"},
+			},
+			"http://svc.ns.svc:80/",
+		},
+		{
+			"TestFullService",
+			&ConsolePluginBackend{
+				Type:    ServiceBackendType,
+				Service: &ConsolePluginService{Name: "svc", Namespace: "ns", Port: 8080, BasePath: "/ui"},
+			},
+			"http://svc.ns.svc:8080/ui",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &ConsolePlugin{Spec: ConsolePluginSpec{Backend: tt.backend}}
+			if got := ConsolePluginLink(cp); got != tt.want {
+				t.Errorf("ConsolePluginLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusReconcilerReconcile(t *testing.T) {
+	obj := newTestConsolePluginUnstructured("test-consoleplugin", map[string]interface{}{
+		"name":      "test-svc",
+		"namespace": "test-ns",
+		"port":      int64(8080),
+	})
+	client := newFakeDynamicClient(obj)
+	r := newStatusReconciler(client, newConsolePluginManager(client).Informer(),
+		NewBackendProber(kubefake.NewSimpleClientset()), NewManifestFetcher())
+	if err := r.informer.GetIndexer().Add(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reconcile(context.Background(), "test-consoleplugin"); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	cp, err := GetConsolePlugin(client, "test-consoleplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://test-svc.test-ns.svc:8080/"; cp.Status.Link != want {
+		t.Errorf("status link = %q, want %q", cp.Status.Link, want)
+	}
+	if IsConsolePluginReady(cp) {
+		t.Errorf("consoleplugin without backend service should not be ready")
+	}
+	if cp.Status.LastError == "" {
+		t.Errorf("status lastError should be recorded")
+	}
+
+	if err = r.reconcile(context.Background(), "not-exist"); err != nil {
+		t.Errorf("reconcile() of missing object error = %v", err)
+	}
+
+	// probing again with the same result leaves the ConsolePlugin untouched
+	patched, err := client.Resource(consolePluginGVR).Get(context.Background(), "test-consoleplugin",
+		metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.informer.GetIndexer().Update(patched); err != nil {
+		t.Fatal(err)
+	}
+	client.ClearActions()
+	if err = r.reconcile(context.Background(), "test-consoleplugin"); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("reconcile() patched the unchanged status")
+		}
+	}
+
+	// an unchanged status is still patched once the recorded probe is too old
+	stale := time.Now().Add(-probeRecordPeriod - time.Minute).UTC().Format(time.RFC3339)
+	if err = unstructured.SetNestedField(patched.Object, stale, "status", "lastProbeTime"); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.informer.GetIndexer().Update(patched); err != nil {
+		t.Fatal(err)
+	}
+	client.ClearActions()
+	if err = r.reconcile(context.Background(), "test-consoleplugin"); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if cp, err = GetConsolePlugin(client, "test-consoleplugin"); err != nil {
+		t.Fatal(err)
+	}
+	if cp.Status.LastProbeTime == nil || time.Since(cp.Status.LastProbeTime.Time) > time.Minute {
+		t.Errorf("status lastProbeTime = %v, want the time of the last probe", cp.Status.LastProbeTime)
+	}
+}
+
+func TestStatusChanged(t *testing.T) {
+	probed := func(result ProbeResult, now time.Time) *ConsolePluginStatus {
+		status := &ConsolePluginStatus{Link: "http://svc.ns.svc:80/"}
+		setBackendConditions(status, &ConsolePlugin{}, result, metav1.NewTime(now))
+		return status
+	}
+	failed := ProbeResult{Resolved: true, Latency: time.Millisecond, Err: context.DeadlineExceeded}
+	old := probed(failed, time.Now())
+
+	failed.Latency = time.Second
+	if statusChanged(old, probed(failed, time.Now().Add(time.Minute))) {
+		t.Errorf("statusChanged() = true for a probe with the same result")
+	}
+	if !statusChanged(old, probed(ProbeResult{Resolved: true, Reachable: true}, time.Now())) {
+		t.Errorf("statusChanged() = false for a recovered backend")
+	}
+}
+
+func TestStatusPatchRemovesClearedFields(t *testing.T) {
+	patch, err := statusPatch(&ConsolePluginStatus{LastError: "timeout", ManifestDigest: "sha256:1"},
+		&ConsolePluginStatus{Link: "http://svc.ns.svc:80/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"status":{"lastError":null,"link":"http://svc.ns.svc:80/","manifestDigest":null}}`
+	if string(patch) != want {
+		t.Errorf("statusPatch() = %s, want %s", patch, want)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ReleaseNamespaceAnnotation is the annotation of the namespace of the Helm release which installed the
+// ConsolePlugin
+const ReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+
+// ErrNoRelease is returned for the ConsolePlugins not installed by a Helm release
+var ErrNoRelease = errors.New("not installed by a Helm release")
+
+var helmReleaseGR = schema.GroupResource{Group: "helm.sh", Resource: "releases"}
+
+// ReleaseInfo describes the Helm release which installed a ConsolePlugin
+type ReleaseInfo struct {
+	Name         string      `json:"name"`
+	Namespace    string      `json:"namespace"`
+	Revision     int         `json:"revision"`
+	Chart        string      `json:"chart"`
+	ChartVersion string      `json:"chartVersion"`
+	AppVersion   string      `json:"appVersion,omitempty"`
+	Status       string      `json:"status"`
+	Description  string      `json:"description,omitempty"`
+	LastDeployed metav1.Time `json:"lastDeployed"`
+}
+
+// ReleaseResolver reads the Helm releases of ConsolePlugins from the Helm storage Secrets
+type ReleaseResolver struct {
+	kubeClient kubernetes.Interface
+}
+
+// NewReleaseResolver returns a new ReleaseResolver
+func NewReleaseResolver(kubeClient kubernetes.Interface) *ReleaseResolver {
+	return &ReleaseResolver{
+		kubeClient: kubeClient,
+	}
+}
+
+// Resolve returns the Helm release named by the release annotations of the ConsolePlugin. The deployed revision
+// is returned, or the last one if none is deployed, e.g. when the first install failed. ErrNoRelease is returned
+// if the ConsolePlugin has no release annotations, a NotFound error if the release does not exist anymore.
+func (r *ReleaseResolver) Resolve(cp *ConsolePlugin) (*ReleaseInfo, error) {
+	name, namespace, err := releaseOf(cp)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := storage.Init(driver.NewSecrets(r.kubeClient.CoreV1().Secrets(namespace)))
+	rls, err := releases.Deployed(name)
+	if err != nil {
+		rls, err = releases.Last(name)
+	}
+	if err != nil {
+		if err = releaseError(name, err); apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading Helm release %s/%s: %w", namespace, name, err)
+	}
+	return newReleaseInfo(rls), nil
+}
+
+func newReleaseInfo(rls *release.Release) *ReleaseInfo {
+	info := &ReleaseInfo{
+		Name:      rls.Name,
+		Namespace: rls.Namespace,
+		Revision:  rls.Version,
+	}
+	if rls.Chart != nil && rls.Chart.Metadata != nil {
+		info.Chart = rls.Chart.Metadata.Name
+		info.ChartVersion = rls.Chart.Metadata.Version
+		info.AppVersion = rls.Chart.Metadata.AppVersion
+	}
+	if rls.Info != nil {
+		info.Status = rls.Info.Status.String()
+		info.Description = rls.Info.Description
+		info.LastDeployed = metav1.NewTime(rls.Info.LastDeployed.Time)
+	}
+	return info
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+var testReleaseTime = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+// newTestRelease returns a revision of a release of the chart test-chart, deployed one hour after the previous
+func newTestRelease(name string, revision int, status release.Status) *release.Release {
+	return &release.Release{
+		Name:      name,
+		Namespace: "test-ns",
+		Version:   revision,
+		Info: &release.Info{
+			Status:       status,
+			Description:  fmt.Sprintf("revision %d", revision),
+			LastDeployed: helmtime.Time{Time: testReleaseTime.Add(time.Duration(revision) * time.Hour)},
+		},
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{
+				Name:       "test-chart",
+				Version:    fmt.Sprintf("1.%d.0", revision),
+				AppVersion: "v1.0",
+			},
+		},
+	}
+}
+
+func TestReleaseResolverResolve(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	secrets := driver.NewSecrets(client.CoreV1().Secrets("test-ns"))
+	for _, rls := range []*release.Release{
+		newTestRelease("upgraded", 1, release.StatusSuperseded),
+		newTestRelease("upgraded", 2, release.StatusDeployed),
+		newTestRelease("failed-upgrade", 1, release.StatusDeployed),
+		newTestRelease("failed-upgrade", 2, release.StatusFailed),
+		newTestRelease("failed-install", 1, release.StatusFailed),
+	} {
+		key := fmt.Sprintf("sh.helm.release.v1.%s.v%d", rls.Name, rls.Version)
+		if err := secrets.Create(key, rls); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolver := NewReleaseResolver(client)
+
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantRevision int
+		wantStatus   string
+		wantErr      func(error) bool
+	}{
+		{"TestDeployed", releaseAnnotations("upgraded", "test-ns"), 2, "deployed", nil},
+		{"TestFailedUpgrade", releaseAnnotations("failed-upgrade", "test-ns"), 1, "deployed", nil},
+		{"TestFailedInstall", releaseAnnotations("failed-install", "test-ns"), 1, "failed", nil},
+		{"TestNoAnnotations", nil, 0, "", func(err error) bool { return errors.Is(err, ErrNoRelease) }},
+		{"TestNoNamespaceAnnotation", map[string]string{ReleaseNameAnnotation: "upgraded"}, 0, "",
+			func(err error) bool { return errors.Is(err, ErrNoRelease) }},
+		{"TestReleaseNotFound", releaseAnnotations("upgraded", "other-ns"), 0, "", apierrors.IsNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &ConsolePlugin{ObjectMeta: metav1.ObjectMeta{Name: "test-plugin", Annotations: tt.annotations}}
+			info, err := resolver.Resolve(cp)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("Resolve() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			wantVersion := fmt.Sprintf("1.%d.0", tt.wantRevision)
+			if info.Revision != tt.wantRevision || info.Status != tt.wantStatus || info.Chart != "test-chart" ||
+				info.ChartVersion != wantVersion || info.AppVersion != "v1.0" {
+				t.Errorf("Resolve() = %+v, want revision %d %s of test-chart %s", info, tt.wantRevision,
+					tt.wantStatus, wantVersion)
+			}
+			wantDeployed := testReleaseTime.Add(time.Duration(tt.wantRevision) * time.Hour)
+			if !info.LastDeployed.Time.Equal(wantDeployed) {
+				t.Errorf("Resolve() lastDeployed = %v, want %v", info.LastDeployed, wantDeployed)
+			}
+		})
+	}
+}
+
+func releaseAnnotations(name, namespace string) map[string]string {
+	return map[string]string{
+		ReleaseNameAnnotation:      name,
+		ReleaseNamespaceAnnotation: namespace,
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	maxNameLength        = 256
+	maxDisplayNameLength = 128
+	maxPort              = 65535
+)
+
+// reservedNames are the names of the collection endpoints under /consoleplugins/, a ConsolePlugin named after
+// one of them could not be reached by /consoleplugins/{pluginName}
+var reservedNames = map[string]struct{}{
+	"events":      {},
+	"order":       {},
+	"enablement":  {},
+	"install":     {},
+	"upload":      {},
+	"marketplace": {},
+}
+
+var (
+	namePattern     = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+	basePathPattern = regexp.MustCompile(`^/[a-zA-Z0-9-]*$`)
+)
+
+// NewInvalidError returns an Invalid status error of the ConsolePlugin with given name and field errors
+func NewInvalidError(name string, errs field.ErrorList) *apierrors.StatusError {
+	return apierrors.NewInvalid(consolePluginGK, name, errs)
+}
+
+// ValidateConsolePlugin validates the name and the spec of a ConsolePlugin
+func ValidateConsolePlugin(cp *ConsolePlugin) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(cp.Name) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), cp.Name, msg))
+	}
+	if _, ok := reservedNames[cp.Name]; ok {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), cp.Name,
+			"is reserved by the endpoint /consoleplugins/"+cp.Name+" of the service"))
+	}
+	allErrs = append(allErrs, ValidateConsolePluginSpec(cp.Name, &cp.Spec, field.NewPath("spec"))...)
+	return allErrs
+}
+
+// ValidateConsolePluginSpec validates the fields of the ConsolePluginSpec of the named ConsolePlugin, including the
+// cross-field rules which could not be expressed by the CRD schema
+func ValidateConsolePluginSpec(name string, spec *ConsolePluginSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateName(spec.PluginName, fldPath.Child("pluginName"))...)
+	allErrs = append(allErrs, validateDisplayName(spec.DisplayName, fldPath.Child("displayName"))...)
+	allErrs = append(allErrs, validateDisplayNames(spec.DisplayNames, fldPath.Child("displayNames"))...)
+
+	if spec.Order != nil && *spec.Order < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("order"), *spec.Order, "must be non-negative"))
+	}
+
+	switch spec.Entrypoint {
+	case NavEntrypoint, SideEntrypoint:
+	case "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("entrypoint"), ""))
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("entrypoint"), spec.Entrypoint,
+			[]string{string(NavEntrypoint), string(SideEntrypoint)}))
+	}
+
+	if len(spec.SubPages) > 0 && spec.Entrypoint != SideEntrypoint {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("subPages"),
+			"only applicable for "+string(SideEntrypoint)+" entrypoint"))
+	}
+	pageNames := make(map[string]struct{}, len(spec.SubPages))
+	for i, page := range spec.SubPages {
+		idxPath := fldPath.Child("subPages").Index(i)
+		allErrs = append(allErrs, validateName(page.PageName, idxPath.Child("pageName"))...)
+		allErrs = append(allErrs, validateDisplayName(page.DisplayName, idxPath.Child("displayName"))...)
+		allErrs = append(allErrs, validateDisplayNames(page.DisplayNames, idxPath.Child("displayNames"))...)
+		allErrs = append(allErrs, validateRequiredAccess(page.RequiredAccess, idxPath.Child("requiredAccess"))...)
+		if _, ok := pageNames[page.PageName]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("pageName"), page.PageName))
+		}
+		pageNames[page.PageName] = struct{}{}
+	}
+
+	allErrs = append(allErrs, validateBackend(spec.Backend, fldPath.Child("backend"))...)
+	allErrs = append(allErrs, validateIcon(spec.Icon, fldPath.Child("icon"))...)
+	allErrs = append(allErrs, validateRequiredAccess(spec.RequiredAccess, fldPath.Child("requiredAccess"))...)
+
+	dependencies := make(map[string]struct{}, len(spec.Dependencies))
+	for i, dependency := range spec.Dependencies {
+		idxPath := fldPath.Child("dependencies").Index(i)
+		allErrs = append(allErrs, validateName(dependency, idxPath)...)
+		// dependencies are the metadata.name of the ConsolePlugins depended on
+		if dependency == name {
+			allErrs = append(allErrs, field.Invalid(idxPath, dependency, "must not depend on itself"))
+		}
+		if _, ok := dependencies[dependency]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath, dependency))
+		}
+		dependencies[dependency] = struct{}{}
+	}
+
+	if spec.ConsoleVersion != "" {
+		if _, err := ParseVersionRange(spec.ConsoleVersion); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("consoleVersion"), spec.ConsoleVersion, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+func validateName(name string, fldPath *field.Path) field.ErrorList {
+	if name == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	var allErrs field.ErrorList
+	if len(name) > maxNameLength {
+		allErrs = append(allErrs, field.TooLong(fldPath, name, maxNameLength))
+	}
+	if !namePattern.MatchString(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, "should only include alphabets, digits and '-'"))
+	}
+	return allErrs
+}
+
+func validateDisplayName(displayName string, fldPath *field.Path) field.ErrorList {
+	if displayName == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return field.ErrorList{field.TooLong(fldPath, displayName, maxDisplayNameLength)}
+	}
+	return nil
+}
+
+func validateBackend(backend *ConsolePluginBackend, fldPath *field.Path) field.ErrorList {
+	if backend == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	var allErrs field.ErrorList
+	if backend.Type != ServiceBackendType {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), backend.Type,
+			[]string{string(ServiceBackendType)}))
+	}
+
+	svcPath := fldPath.Child("service")
+	svc := backend.Service
+	if svc == nil {
+		return append(allErrs, field.Required(svcPath, "required for "+string(ServiceBackendType)+" backend"))
+	}
+	allErrs = append(allErrs, validateName(svc.Name, svcPath.Child("name"))...)
+	allErrs = append(allErrs, validateName(svc.Namespace, svcPath.Child("namespace"))...)
+	if svc.Port < 0 || svc.Port > maxPort {
+		allErrs = append(allErrs, field.Invalid(svcPath.Child("port"), svc.Port, "must be between 1 and 65535"))
+	}
+	if svc.BasePath != "" && !basePathPattern.MatchString(svc.BasePath) {
+		allErrs = append(allErrs, field.Invalid(svcPath.Child("basePath"), svc.BasePath,
+			"must start with '/' followed by alphabets, digits and '-'"))
+	}
+	return allErrs
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func newValidTestSpec() ConsolePluginSpec {
+	return ConsolePluginSpec{
+		PluginName:  "test-plugin",
+		DisplayName: "Test Plugin",
+		Entrypoint:  SideEntrypoint,
+		SubPages: []ConsolePluginName{
+			{PageName: "page-1", DisplayName: "Page 1"},
+		},
+		Backend: &ConsolePluginBackend{
+			Type: ServiceBackendType,
+			Service: &ConsolePluginService{
+				Name:      "test-svc",
+				Namespace: "test-ns",
+				Port:      8080,
+				BasePath:  "/ui",
+			},
+		},
+		Enabled: true,
+	}
+}
+
+func TestValidateConsolePlugin(t *testing.T) {
+	negativeOrder := int64(-1)
+	tests := []struct {
+		name       string
+		objName    string
+		mutate     func(spec *ConsolePluginSpec)
+		wantFields []string
+	}{
+		{
+			"TestValid",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) {},
+			nil,
+		},
+		{
+			"TestReservedObjectName",
+			"order",
+			func(spec *ConsolePluginSpec) {},
+			[]string{"metadata.name"},
+		},
+		{
+			"TestInvalidObjectName",
+			"Test_Plugin",
+			func(spec *ConsolePluginSpec) {},
+			[]string{"metadata.name"},
+		},
+		{
+			"TestInvalidPluginName",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.PluginName = "test plugin" },
+			[]string{"spec.pluginName"},
+		},
+		{
+			"TestDisplayNameTooLong",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.DisplayName = strings.Repeat("扩", maxDisplayNameLength+1) },
+			[]string{"spec.displayName"},
+		},
+		{
+			"TestInvalidDisplayNames",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) {
+				spec.DisplayNames = map[string]string{"not a locale": "Plugin"}
+				spec.SubPages[0].DisplayNames = map[string]string{"zh-CN": ""}
+			},
+			[]string{"spec.displayNames[not a locale]", "spec.subPages[0].displayNames[zh-CN]"},
+		},
+		{
+			"TestInvalidRequiredAccess",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) {
+				spec.RequiredAccess = []ConsolePluginAccess{{Verb: "Get", Resource: "clusters"}}
+				spec.SubPages[0].RequiredAccess = []ConsolePluginAccess{{Verb: "get"}}
+			},
+			[]string{"spec.subPages[0].requiredAccess[0].resource", "spec.requiredAccess[0].verb"},
+		},
+		{
+			"TestNegativeOrder",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.Order = &negativeOrder },
+			[]string{"spec.order"},
+		},
+		{
+			"TestUnsupportedEntrypoint",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.Entrypoint = "/" },
+			[]string{"spec.entrypoint", "spec.subPages"},
+		},
+		{
+			"TestSubPagesWithNavEntrypoint",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.Entrypoint = NavEntrypoint },
+			[]string{"spec.subPages"},
+		},
+		{
+			"TestDuplicateSubPages",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.SubPages = append(spec.SubPages, spec.SubPages[0]) },
+			[]string{"spec.subPages[1].pageName"},
+		},
+		{
+			"TestMissingBackend",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) { spec.Backend = nil },
+			[]string{"spec.backend"},
+		},
+		{
+			"TestInvalidBackendService",
+			"test-plugin",
+			func(spec *ConsolePluginSpec) {
+				spec.Backend.Service.Port = 70000
+				spec.Backend.Service.BasePath = "ui"
+			},
+			[]string{"spec.backend.service.port", "spec.backend.service.basePath"},
+		},
+		{
+			"TestSelfDependency",
+			"test-object",
+			func(spec *ConsolePluginSpec) { spec.Dependencies = []string{"test-object"} },
+			[]string{"spec.dependencies[0]"},
+		},
+		{
+			"TestDependencyOnPluginName",
+			"test-object",
+			func(spec *ConsolePluginSpec) { spec.Dependencies = []string{"test-plugin"} },
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &ConsolePlugin{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.objName},
+				Spec:       newValidTestSpec(),
+			}
+			tt.mutate(&cp.Spec)
+			errs := ValidateConsolePlugin(cp)
+			if got := errorFields(errs); strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("ValidateConsolePlugin() fields = %v, want %v (%v)", got, tt.wantFields, errs)
+			}
+		})
+	}
+}
+
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
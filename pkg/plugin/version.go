@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// ReasonIncompatible is the reason reported for a ConsolePlugin which does not support the running console version
+const ReasonIncompatible = "Incompatible"
+
+// versionConstraint is a single comparison of a version range, e.g. >=1.2
+type versionConstraint struct {
+	operator string
+	version  *version.Version
+}
+
+func (c versionConstraint) matches(v *version.Version) bool {
+	switch c.operator {
+	case ">":
+		return c.version.LessThan(v)
+	case ">=":
+		return v.AtLeast(c.version)
+	case "<":
+		return v.LessThan(c.version)
+	case "<=":
+		return !c.version.LessThan(v)
+	case "!=":
+		return v.LessThan(c.version) || c.version.LessThan(v)
+	default:
+		return v.AtLeast(c.version) && !c.version.LessThan(v)
+	}
+}
+
+// VersionRange is a range of versions like ">=1.2 <2.0 || >=3.0". Constraints separated by spaces must all
+// be satisfied, and the range is satisfied if any of the alternatives separated by "||" is satisfied.
+// Supported operators are [>, >=, <, <=, =, !=], a version without an operator means "=".
+type VersionRange [][]versionConstraint
+
+// ParseVersionRange parses a version range
+func ParseVersionRange(s string) (VersionRange, error) {
+	var r VersionRange
+	for _, alternative := range strings.Split(s, "||") {
+		fields := strings.Fields(alternative)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty constraint in version range %q", s)
+		}
+		constraints := make([]versionConstraint, 0, len(fields))
+		for _, f := range fields {
+			c, err := parseVersionConstraint(f)
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, c)
+		}
+		r = append(r, constraints)
+	}
+	return r, nil
+}
+
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	c := versionConstraint{operator: "="}
+	for _, operator := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(s, operator) {
+			c.operator = operator
+			s = s[len(operator):]
+			break
+		}
+	}
+	v, err := version.ParseGeneric(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid version %q in constraint: %v", s, err)
+	}
+	c.version = v
+	return c, nil
+}
+
+// Contains returns whether the version satisfies the range
+func (r VersionRange) Contains(v *version.Version) bool {
+	for _, constraints := range r {
+		matched := true
+		for _, c := range constraints {
+			if !c.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCompatibility returns an error if the ConsolePlugin does not support the running console version.
+// Every ConsolePlugin is compatible if the console version is not configured.
+func (cm *ConsolePluginManager) CheckCompatibility(cp *ConsolePlugin) error {
+	if cm.ConsoleVersion == nil || cp.Spec.ConsoleVersion == "" {
+		return nil
+	}
+	r, err := ParseVersionRange(cp.Spec.ConsoleVersion)
+	if err != nil {
+		return err
+	}
+	if !r.Contains(cm.ConsoleVersion) {
+		return fmt.Errorf("requires console version %q, but the console version is %s",
+			cp.Spec.ConsoleVersion, cm.ConsoleVersion)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package plugin
+
+import (
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		version      string
+		want         bool
+	}{
+		{">=1.2 <2.0", "1.2", true},
+		{">=1.2 <2.0", "1.9.3", true},
+		{">=1.2 <2.0", "2.0.0", false},
+		{">=1.2 <2.0", "1.1.9", false},
+		{">1.2", "1.2.0", false},
+		{"<=1.2", "1.2.0", true},
+		{"1.2.3", "v1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"!=1.2.3", "1.2.4", true},
+		{"<1.0 || >=2.0", "2.1", true},
+		{"<1.0 || >=2.0", "1.5", false},
+	}
+	for _, tt := range tests {
+		r, err := ParseVersionRange(tt.versionRange)
+		if err != nil {
+			t.Fatalf("ParseVersionRange(%q) error = %v", tt.versionRange, err)
+		}
+		if got := r.Contains(version.MustParseGeneric(tt.version)); got != tt.want {
+			t.Errorf("%q.Contains(%s) = %t, want %t", tt.versionRange, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionRangeInvalid(t *testing.T) {
+	for _, s := range []string{"", ">=1.2 ||", ">=abc", "~>1.2"} {
+		if _, err := ParseVersionRange(s); err == nil {
+			t.Errorf("ParseVersionRange(%q) should fail", s)
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	cm := newTestManager(testDependencyGraph...)
+	cp := &ConsolePlugin{Spec: ConsolePluginSpec{ConsoleVersion: ">=1.2 <2.0"}}
+	if err := cm.CheckCompatibility(cp); err != nil {
+		t.Errorf("CheckCompatibility() without console version error = %v", err)
+	}
+
+	cm.ConsoleVersion = version.MustParseGeneric("2.0.0")
+	if err := cm.CheckCompatibility(cp); err == nil {
+		t.Errorf("CheckCompatibility() should fail for console version 2.0.0")
+	}
+	cp.Spec.ConsoleVersion = ""
+	if err := cm.CheckCompatibility(cp); err != nil {
+		t.Errorf("CheckCompatibility() without range error = %v", err)
+	}
+}
+
+func TestSetPluginEnablementIncompatible(t *testing.T) {
+	cm := newTestManager(
+		testConsolePlugin{name: "old", disabled: true, consoleVersion: "<2.0"},
+		testConsolePlugin{name: "new", disabled: true, dependencies: []string{"old"}},
+	)
+	cm.ConsoleVersion = version.MustParseGeneric("2.1.0")
+
+	if _, err := cm.SetPluginEnablement("old", true, false); !apierrors.IsConflict(err) {
+		t.Errorf("SetPluginEnablement() error = %v, want conflict", err)
+	}
+	if _, err := cm.SetPluginEnablement("new", true, true); !apierrors.IsConflict(err) {
+		t.Errorf("SetPluginEnablement() in cascade error = %v, want conflict", err)
+	}
+	if enabled, _ := cm.CheckPluginEnablementIfInstalled("old"); enabled {
+		t.Errorf("incompatible ConsolePlugin should not be enabled")
+	}
+}
@@ -14,6 +14,12 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
 	"plugin-management-service/pkg/client/k8s"
 	"plugin-management-service/pkg/server/runtime"
 )
@@ -22,20 +28,58 @@ import (
 type RunConfig struct {
 	Server        *runtime.ServerConfig
 	KubernetesCfg *k8s.KubernetesCfg
+
+	// ConsoleVersion is the version of the running console, ConsolePlugins requiring another console
+	// version are incompatible. Compatibility is not checked if empty.
+	ConsoleVersion string
+
+	// ClientCertAuth authenticates the users presenting a client certificate signed by the client CA, in
+	// addition to the bearer tokens
+	ClientCertAuth bool
+
+	// ChartUploadLimitMB is the maximum size in MiB of a chart archive uploaded to install ConsolePlugins offline
+	ChartUploadLimitMB int64
 }
 
+// DefaultChartUploadLimitMB is the default maximum size in MiB of an uploaded chart archive
+const DefaultChartUploadLimitMB = 20
+
 // NewRunConfig creates a new RunConfig with default values
 func NewRunConfig() *RunConfig {
 	return &RunConfig{
-		Server:        runtime.NewServerConfig(),
-		KubernetesCfg: k8s.NewKubernetesCfg(),
+		Server:             runtime.NewServerConfig(),
+		KubernetesCfg:      k8s.NewKubernetesCfg(),
+		ConsoleVersion:     os.Getenv("CONSOLE_VERSION"),
+		ClientCertAuth:     os.Getenv("CLIENT_CERT_AUTH") == "true",
+		ChartUploadLimitMB: chartUploadLimitFromEnv(),
 	}
 }
 
+// chartUploadLimitFromEnv reads the chart upload limit from CHART_UPLOAD_LIMIT_MB, -1 if it is not a number
+func chartUploadLimitFromEnv() int64 {
+	limit := os.Getenv("CHART_UPLOAD_LIMIT_MB")
+	if limit == "" {
+		return DefaultChartUploadLimitMB
+	}
+	limitMB, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return limitMB
+}
+
 // Validate the RunConfig
 func (cfg *RunConfig) Validate() []error {
 	var errs []error
 	errs = append(errs, cfg.Server.Validate()...)
 	errs = append(errs, cfg.KubernetesCfg.Validate()...)
+	if cfg.ConsoleVersion != "" {
+		if _, err := version.ParseGeneric(cfg.ConsoleVersion); err != nil {
+			errs = append(errs, fmt.Errorf("invalid console version: %v", err))
+		}
+	}
+	if cfg.ChartUploadLimitMB <= 0 {
+		errs = append(errs, fmt.Errorf("invalid chart upload limit: must be a positive number of MiB"))
+	}
 	return errs
 }
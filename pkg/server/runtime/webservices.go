@@ -24,6 +24,9 @@ import (
 const (
 	// ApiRootPath of plugin-management-service
 	ApiRootPath = "/rest"
+
+	// AdmissionSubPath of the admission webhooks served by plugin-management-service
+	AdmissionSubPath = "admission"
 )
 
 var (
@@ -33,11 +36,14 @@ var (
 	}
 
 	webService *restful.WebService
+
+	admissionWebService *restful.WebService
 )
 
 func init() {
 	initRestfulRegister()
 	webService = NewRestfulWebService(groupVersion)
+	admissionWebService = NewWebServiceFromStr(AdmissionSubPath)
 }
 
 // NewRestfulWebService create a webservice with group-version string in root path
@@ -58,6 +64,11 @@ func GetPluginWebService() *restful.WebService {
 	return webService
 }
 
+// GetAdmissionWebService get admission webhook web service
+func GetAdmissionWebService() *restful.WebService {
+	return admissionWebService
+}
+
 func initRestfulRegister() {
 	restful.RegisterEntityAccessor("application/merge-patch+json", restful.NewEntityAccessorJSON(restful.MIME_JSON))
 	restful.RegisterEntityAccessor("application/json-patch+json", restful.NewEntityAccessorJSON(restful.MIME_JSON))
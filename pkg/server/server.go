@@ -21,9 +21,13 @@ import (
 	"os"
 
 	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/util/version"
 
+	admissionv1 "plugin-management-service/pkg/api/admission/v1"
 	pluginv1beta1 "plugin-management-service/pkg/api/consoleplugin/v1beta1"
+	"plugin-management-service/pkg/auth"
 	"plugin-management-service/pkg/client/k8s"
+	"plugin-management-service/pkg/plugin"
 	"plugin-management-service/pkg/server/config"
 	"plugin-management-service/pkg/server/runtime"
 	"plugin-management-service/pkg/zlog"
@@ -34,13 +38,30 @@ type CServer struct {
 	// server
 	Server *http.Server
 
-	// Container a Web Server（服务器），con WebServices 组成，此外还包含了若干个 Filters（过滤器）、
+	// Container a Web Server（服务器），con WebServices 组成，
+	// 此外还包含了若干个 Filters（过滤器）、
 	container *restful.Container
 
 	// helm用到的k8s client
 	KubernetesClient k8s.BaseClient
+
+	// pluginManager serves ConsolePlugin reads from its cache and is shared by the APIs and the reconciler
+	pluginManager *plugin.ConsolePluginManager
+
+	// statusReconciler keeps the status of ConsolePlugin resources up to date
+	statusReconciler *plugin.StatusReconciler
+
+	// authenticator puts the user calling the plugin APIs on the request
+	authenticator *auth.Authenticator
+
+	// maxChartUploadBytes is the maximum size of a chart archive uploaded to install ConsolePlugins
+	maxChartUploadBytes int64
 }
 
+const (
+	statusReconcilerWorkers = 2
+)
+
 // NewServer creates an cServer instance using given options
 func NewServer(cfg *config.RunConfig, ctx context.Context) (*CServer, error) {
 	server := &CServer{}
@@ -61,6 +82,30 @@ func NewServer(cfg *config.RunConfig, ctx context.Context) (*CServer, error) {
 	}
 	server.KubernetesClient = kubernetesClient
 
+	pluginManager, err := plugin.NewConsolePluginManager(kubernetesClient.ConfigClient())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ConsoleVersion != "" {
+		pluginManager.ConsoleVersion, err = version.ParseGeneric(cfg.ConsoleVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	server.pluginManager = pluginManager
+
+	statusReconciler, err := plugin.NewStatusReconciler(kubernetesClient.ConfigClient(), pluginManager)
+	if err != nil {
+		return nil, err
+	}
+	server.statusReconciler = statusReconciler
+
+	server.authenticator = &auth.Authenticator{
+		Tokens:      auth.NewTokenAuthenticator(kubernetesClient.KubernetesClient()),
+		ClientCerts: cfg.ClientCertAuth && cfg.Server.SecurePort != 0,
+	}
+	server.maxChartUploadBytes = cfg.ChartUploadLimitMB << 20
+
 	return server, nil
 }
 
@@ -102,6 +147,8 @@ func (s *CServer) Run(ctx context.Context) error {
 	var err error = nil
 	s.registerAPI()
 	s.Server.Handler = s.container
+	s.pluginManager.Start(ctx)
+	go s.statusReconciler.Run(ctx, statusReconcilerWorkers)
 
 	shutdownCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -121,6 +168,12 @@ func (s *CServer) Run(ctx context.Context) error {
 
 func (s *CServer) registerAPI() {
 	pluginWebService := runtime.GetPluginWebService()
-	pluginv1beta1.BindPluginRoute(pluginWebService, s.KubernetesClient.ConfigClient())
+	pluginWebService.Filter(s.authenticator.Filter)
+	pluginv1beta1.BindPluginRoute(pluginWebService, s.KubernetesClient.ConfigClient(), s.pluginManager,
+		s.maxChartUploadBytes)
 	s.container.Add(pluginWebService)
+
+	admissionWebService := runtime.GetAdmissionWebService()
+	admissionv1.BindAdmissionRoute(admissionWebService, s.pluginManager)
+	s.container.Add(admissionWebService)
 }
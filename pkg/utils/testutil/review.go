@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+// Package testutil provides the fake clients shared by the tests of several packages
+package testutil
+
+import (
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// NewTokenReviewClient returns a fake client whose TokenReviews are answered by review, an error failing the
+// request. The reviews are counted in reviews unless nil.
+func NewTokenReviewClient(reviews *int, review func(*authenticationv1.TokenReview) error) *kubefake.Clientset {
+	return newReviewClient("tokenreviews", reviews, func(obj k8sruntime.Object) error {
+		return review(obj.(*authenticationv1.TokenReview))
+	})
+}
+
+// NewSubjectAccessReviewClient returns a fake client whose SubjectAccessReviews are answered by review, an error
+// failing the request. The reviews are counted in reviews unless nil.
+func NewSubjectAccessReviewClient(reviews *int,
+	review func(*authorizationv1.SubjectAccessReview) error) *kubefake.Clientset {
+	return newReviewClient("subjectaccessreviews", reviews, func(obj k8sruntime.Object) error {
+		return review(obj.(*authorizationv1.SubjectAccessReview))
+	})
+}
+
+func newReviewClient(resource string, reviews *int, review func(k8sruntime.Object) error) *kubefake.Clientset {
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("create", resource,
+		func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+			if reviews != nil {
+				*reviews++
+			}
+			obj := action.(clienttesting.CreateAction).GetObject()
+			if err := review(obj); err != nil {
+				return true, nil, err
+			}
+			return true, obj, nil
+		})
+	return client
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 Huawei Technologies Co., Ltd.
+ * openFuyao is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package zlog
+
+import (
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var auditLogger *zap.SugaredLogger
+
+// auditConfig configures the audit log, always written as JSON lines so it can be shipped and queried
+type auditConfig struct {
+	// Path defaults to the path of the service log
+	Path       string
+	FileName   string
+	MaxSize    int
+	MaxBackups int
+	MaxAge     int
+	LocalTime  bool
+	Compress   bool
+	OutMod     string
+}
+
+func getDefaultAuditConf() auditConfig {
+	return auditConfig{
+		FileName:   "audit.log",
+		MaxSize:    20,
+		MaxBackups: 10,
+		MaxAge:     90,
+		LocalTime:  false,
+		Compress:   true,
+		OutMod:     "file",
+	}
+}
+
+func getAuditLogger(conf *logConfig) *zap.SugaredLogger {
+	audit := conf.Audit
+	// 未单独配置目录时与服务日志放在同一目录
+	if audit.Path == "" {
+		audit.Path = conf.Path
+	}
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), getAuditWriter(&audit), zapcore.InfoLevel)
+	return zap.New(core).Sugar()
+}
+
+func getAuditWriter(audit *auditConfig) zapcore.WriteSyncer {
+	if audit.OutMod == "console" {
+		return zapcore.AddSync(os.Stdout)
+	}
+	lumberJackLogger := &lumberjack.Logger{
+		Filename:   filepath.Join(audit.Path, audit.FileName),
+		MaxSize:    audit.MaxSize,
+		MaxBackups: audit.MaxBackups,
+		MaxAge:     audit.MaxAge,
+		LocalTime:  audit.LocalTime,
+		Compress:   audit.Compress,
+	}
+	if audit.OutMod == "both" {
+		return zapcore.NewMultiWriteSyncer(zapcore.AddSync(lumberJackLogger), zapcore.AddSync(os.Stdout))
+	}
+	return zapcore.AddSync(lumberJackLogger)
+}
+
+// Audit writes an entry with the message and the key-value pairs to the audit log
+func Audit(msg string, keysAndValues ...interface{}) {
+	auditLogger.Infow(msg, keysAndValues...)
+}
@@ -57,6 +57,9 @@ type logConfig struct {
 	LocalTime   bool
 	Compress    bool
 	OutMod      string
+
+	// Audit configures the audit log stream, rotated apart from the service log
+	Audit auditConfig
 }
 
 func init() {
@@ -67,6 +70,7 @@ func init() {
 		conf = getDefaultConf()
 	}
 	logger = getLogger(conf)
+	auditLogger = getAuditLogger(conf)
 }
 
 func loadConfig() (*logConfig, error) {
@@ -95,6 +99,7 @@ func getDefaultConf() *logConfig {
 		LocalTime:   false,
 		Compress:    true,
 		OutMod:      "both",
+		Audit:       getDefaultAuditConf(),
 	}
 	exePath, err := os.Executable()
 	if err != nil {
@@ -130,6 +135,7 @@ func watchConfig() {
 				logger.Warnf("Error reloading config file: %v\n", err)
 			} else {
 				logger = getLogger(conf)
+				auditLogger = getAuditLogger(conf)
 			}
 		})
 	})
@@ -140,7 +146,7 @@ func parseConfig() (*logConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	var config logConfig
+	config := logConfig{Audit: getDefaultAuditConf()}
 	err = viper.Unmarshal(&config)
 	if err != nil {
 		return nil, err